@@ -42,13 +42,13 @@ func NewApplyCommand(root *cobra.Command) {
 		Args:  lib.ArgsValidator,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
-			tf, ctx, files, _ := lib.Executor(*cmd, args[0], args[1], true)
+			tf, ctx, set := lib.Executor(*cmd, args[0], args[1], true)
 
-			planFile := fmt.Sprintf("%s-%s.tfplan", strings.Replace(time.Now().Format(time.RFC3339), ":", "-", -1), args[0])
+			planFile := fmt.Sprintf("%s-%s.tfplan", strings.Replace(time.Now().Format(time.RFC3339), ":", "-", -1), set.Workspace)
 			planFile, _ = filepath.Abs(planFile)
 
 			//plan
-			_, err := tf.Plan(ctx, buildPlanOptions(files, args, planFile)...)
+			_, err := tf.Plan(ctx, buildPlanOptions(set, planFile)...)
 
 			if err != nil {
 				return err
@@ -69,19 +69,26 @@ func NewApplyCommand(root *cobra.Command) {
 		},
 	}
 
+	applyCmd.Flags().Bool("strict", false, "fail on warnings of the var validation")
+	addOverrideFlags(applyCmd)
+
 	root.AddCommand(applyCmd)
 }
 
-func buildPlanOptions(files []string, args []string, planFile string) []tfexec.PlanOption {
+func buildPlanOptions(set *lib.VarSet, planFile string) []tfexec.PlanOption {
 	var planops []tfexec.PlanOption
 
-	for _, f := range files {
+	for _, f := range set.Files {
 		planops = append(planops, tfexec.VarFile(f))
 	}
 
+	for _, v := range set.InjectedVars() {
+		planops = append(planops, tfexec.Var(v))
+	}
+
 	if planFile != "" {
-		return append(planops, tfexec.Var(fmt.Sprintf("environment=%s", args[0])), tfexec.Out(planFile))
+		return append(planops, tfexec.Out(planFile))
 	}
 
-	return append(planops, tfexec.Var(fmt.Sprintf("environment=%s", args[0])))
+	return planops
 }
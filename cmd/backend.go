@@ -0,0 +1,247 @@
+// Package cmd
+/*
+Copyright © 2022 Robert Schönthal <robert@schoenthal.io>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/terrarium-tf/cli/lib"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Backend configures the remote state of a terraform backend type
+type Backend interface {
+	// Name is the backend type as declared in the stack, e.g. s3 for `backend "s3" {}`
+	Name() string
+	// RequiredVars are the variables the backend can't be configured without
+	RequiredVars() []string
+	// Naming are the default templates of the generated options (bucket, key, ...) by option name,
+	// they are used if the option is not set by a variable and can be changed in the project config
+	Naming() map[string]string
+	// Configure builds the -backend-config options
+	Configure(cmd cobra.Command, set *lib.VarSet, stackPath string) []BackendOption
+}
+
+// BackendOption is a -backend-config option and where its value comes from
+type BackendOption struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Source is a var file, a flag, an environment variable or a generated default
+	Source string `json:"source"`
+	// Vars are the variables the value is read or generated from, the value is redacted if one of them is sensitive
+	Vars []string `json:"-"`
+}
+
+func (o BackendOption) String() string {
+	return fmt.Sprintf("%s=%s", o.Key, o.Value)
+}
+
+const (
+	sourceGenerated = "generated default"
+	sourceNaming    = "generated by naming config"
+)
+
+// defaultBackend is used for stacks without a backend statement
+const defaultBackend = "s3"
+
+// backends are the supported backends by type
+var backends = map[string]Backend{}
+
+// RegisterBackend makes a backend available for stacks declaring its type
+func RegisterBackend(b Backend) {
+	backends[b.Name()] = b
+}
+
+func init() {
+	for _, b := range []Backend{s3Backend{}, gcsBackend{}, azurermBackend{}, pgBackend{}, consulBackend{}, httpBackend{}, kubernetesBackend{}, localBackend{}, cloudBackend{}} {
+		RegisterBackend(b)
+	}
+}
+
+// resolveBackend detects the backend of the stack and builds its options, it exits if the backend can't be configured,
+// it also returns a description of where the backend was declared
+func resolveBackend(cmd cobra.Command, set *lib.VarSet, stackPath string) (Backend, string, []BackendOption) {
+	// injected variables are meant for the stack only
+	set = set.WithoutInjected()
+
+	backend, source := detectBackend(cmd, stackPath)
+	for _, name := range backend.RequiredVars() {
+		backendVar(name, cmd, set.Vars, true)
+	}
+	validateNaming(cmd, set, backend)
+
+	return backend, source, backend.Configure(cmd, set, stackPath)
+}
+
+// detectBackend finds the backend of the stack and exits if terrarium can't configure it,
+// it also returns a description of where the backend was declared
+func detectBackend(cmd cobra.Command, stackPath string) (Backend, string) {
+	name, source, err := detectBackendType(stackPath)
+	if err != nil {
+		cmd.PrintErrf(lib.ErrorColorLine, fmt.Sprintf("unable to detect the backend: %s", err))
+		lib.Exit(1)
+	}
+
+	backend, ok := backends[name]
+	if !ok {
+		names := make([]string, 0, len(backends))
+		for n := range backends {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		cmd.PrintErrf(lib.ErrorColorLine, fmt.Sprintf("unable to configure remote state, backend '%s' is not supported, use one of %s or --remote-state=false", name, strings.Join(names, ", ")))
+		lib.Exit(1)
+	}
+	return backend, source
+}
+
+// detectBackendType reads the backend block of the stack's root module, stacks without one use the defaultBackend
+func detectBackendType(stackPath string) (string, string, error) {
+	backend, err := lib.FindStackBackend(stackPath)
+	if err != nil {
+		return "", "", err
+	}
+	if backend == nil {
+		return defaultBackend, fmt.Sprintf("%s (default, no backend declared)", defaultBackend), nil
+	}
+	return backend.Type, backend.String(), nil
+}
+
+// backendVar prefers a structured "backend" object in the var files over top-level vars, e.g. backend.bucket over bucket
+func backendVar(name string, cmd cobra.Command, mergedVars map[string]any, required bool) string {
+	if v := lib.GetVar("backend."+name, cmd, mergedVars, false); v != "" {
+		return v
+	}
+	return lib.GetVar(name, cmd, mergedVars, required)
+}
+
+// backendVarName tells which variable backendVar reads, it is empty if the value is given with the flag
+func backendVarName(name string, cmd cobra.Command, set *lib.VarSet) string {
+	if flag := cmd.Flags().Lookup(lib.StateFlag(name)); flag != nil && flag.Changed {
+		return ""
+	}
+	if lib.GetVar("backend."+name, cmd, set.Vars, false) != "" {
+		return "backend." + name
+	}
+	return name
+}
+
+// backendVarSource tells where backendVar finds the variable, its flag or the var file which set it
+func backendVarSource(name string, cmd cobra.Command, set *lib.VarSet) string {
+	if v := backendVarName(name, cmd, set); v != "" {
+		return set.VarOrigin(v)
+	}
+	return fmt.Sprintf("flag --%s", lib.StateFlag(name))
+}
+
+// varOption reads the option from the variable name, it is false if the variable is not set
+func varOption(key string, name string, cmd cobra.Command, set *lib.VarSet, required bool) (BackendOption, bool) {
+	v := backendVar(name, cmd, set.Vars, required)
+	if v == "" {
+		return BackendOption{}, false
+	}
+
+	o := BackendOption{Key: key, Value: v, Source: backendVarSource(name, cmd, set)}
+	if n := backendVarName(name, cmd, set); n != "" {
+		o.Vars = []string{n}
+	}
+	return o, true
+}
+
+// varOrEnvOption falls back to the first environment variable which is set
+func varOrEnvOption(key string, name string, cmd cobra.Command, set *lib.VarSet, envs ...string) (BackendOption, bool) {
+	if o, ok := varOption(key, name, cmd, set, false); ok {
+		return o, true
+	}
+	for _, env := range envs {
+		if v := os.Getenv(env); v != "" {
+			return BackendOption{Key: key, Value: v, Source: fmt.Sprintf("env %s", env)}, true
+		}
+	}
+	return BackendOption{}, false
+}
+
+// optionalBackendConfigs passes the given variables only if they are set
+func optionalBackendConfigs(cmd cobra.Command, set *lib.VarSet, names ...string) []BackendOption {
+	var opts []BackendOption
+	for _, name := range names {
+		if o, ok := varOption(name, name, cmd, set, false); ok {
+			opts = append(opts, o)
+		}
+	}
+	return opts
+}
+
+// generatedOption renders the naming template of the backend option, the project config wins over the default
+func generatedOption(cmd cobra.Command, set *lib.VarSet, stackPath string, b Backend, option string) BackendOption {
+	o := BackendOption{Key: option, Source: sourceNaming}
+	text := set.Project.Naming(b.Name(), option)
+	if text == "" {
+		text, o.Source = b.Naming()[option], sourceGenerated
+	}
+
+	var err error
+	o.Value, err = lib.RenderNaming(b.Name()+"."+option, text, lib.NewNamingData(set, stackPath), func(name string, required bool) string {
+		v := backendVar(name, cmd, set.Vars, required)
+		if n := backendVarName(name, cmd, set); v != "" && n != "" {
+			o.Vars = appendUnique(o.Vars, n)
+		}
+		return v
+	})
+	if err != nil {
+		cmd.PrintErrf(lib.ErrorColorLine, fmt.Sprintf("unable to configure remote state, %s", err))
+		lib.Exit(1)
+	}
+	return o
+}
+
+// validateNaming fails for naming templates of options the backend doesnt generate
+func validateNaming(cmd cobra.Command, set *lib.VarSet, b Backend) {
+	for _, option := range set.Project.NamingOptions(b.Name()) {
+		if _, ok := b.Naming()[option]; !ok {
+			cmd.PrintErrf(lib.ErrorColorLine, fmt.Sprintf("unable to configure remote state, backend '%s' doesnt generate '%s'", b.Name(), option))
+			lib.Exit(1)
+		}
+	}
+}
+
+// configureStateKey uses the "name" variable as the state file name or generates the key
+func configureStateKey(cmd cobra.Command, set *lib.VarSet, stackPath string, b Backend) BackendOption {
+	if o, ok := varOption("key", "name", cmd, set, false); ok {
+		o.Value = o.Value + ".tfstate"
+		return o
+	}
+	return generatedOption(cmd, set, stackPath, b, "key")
+}
+
+// configureGenerated uses the variable of the option or generates it
+func configureGenerated(cmd cobra.Command, set *lib.VarSet, stackPath string, b Backend, option string, variable string) BackendOption {
+	if o, ok := varOption(option, variable, cmd, set, false); ok {
+		return o
+	}
+	// not defined, so generate a unique name
+	return generatedOption(cmd, set, stackPath, b, option)
+}
@@ -0,0 +1,143 @@
+// Package cmd
+/*
+Copyright © 2022 Robert Schönthal <robert@schoenthal.io>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/spf13/cobra"
+	"github.com/terrarium-tf/cli/lib"
+	"github.com/zclconf/go-cty/cty"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+type backendOutput struct {
+	Type     string          `json:"type"`
+	Declared string          `json:"declared"`
+	Options  []BackendOption `json:"options"`
+}
+
+func NewBackendCommand(root *cobra.Command) {
+	var backendCmd = &cobra.Command{
+		Use:   "backend",
+		Short: "Inspects the remote state configuration of stacks",
+	}
+
+	var showCmd = &cobra.Command{
+		Use:   "show workspace path/to/stack [--output=table|json] [--write=backend.hcl]",
+		Short: "Prints the resolved backend configuration of a stack without running init",
+		Long: `Detects the backend of the stack and resolves its configuration like init does,
+every option is printed with its source: a var file, a flag, an environment variable or a generated default.
+Sensitive values are redacted in the output, --write stores the configuration (unredacted) as a file
+for "terraform init -backend-config=backend.hcl".`,
+		Example: "backend show prod path/to/stack --write=backend.hcl",
+		Args:    lib.ArgsValidator,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			set, err := lib.CollectVars(args[0], args[1], lib.CliOverrides(*cmd))
+			if err != nil {
+				return err
+			}
+
+			backend, declared, options := resolveBackend(*cmd, set, args[1])
+
+			if file, _ := cmd.Flags().GetString("write"); file != "" {
+				if err := writeBackendConfig(file, options); err != nil {
+					return err
+				}
+			}
+
+			out := backendOutput{Type: backend.Name(), Declared: declared, Options: make([]BackendOption, 0, len(options))}
+			for _, o := range options {
+				o.Value = redactBackendOption(set, o)
+				out.Options = append(out.Options, o)
+			}
+
+			output, _ := cmd.Flags().GetString("output")
+			switch output {
+			case "json":
+				content, err := json.MarshalIndent(out, "", "  ")
+				if err != nil {
+					return err
+				}
+				cmd.Println(string(content))
+				return nil
+			case "table":
+				return printBackendTable(cmd, out)
+			default:
+				return fmt.Errorf("unknown output format '%s', use table or json", output)
+			}
+		},
+	}
+
+	showCmd.Flags().StringP("output", "o", "table", "output format: table or json")
+	showCmd.Flags().String("write", "", "write the backend configuration to the given file, e.g. backend.hcl")
+	addStateFlags(showCmd)
+	addOverrideFlags(showCmd)
+
+	backendCmd.AddCommand(showCmd)
+	root.AddCommand(backendCmd)
+}
+
+func printBackendTable(cmd *cobra.Command, out backendOutput) error {
+	cmd.Printf("backend: %s\n\n", out.Declared)
+	if len(out.Options) == 0 {
+		cmd.Println("no backend config")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, o := range out.Options {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", o.Key, o.Value, o.Source)
+	}
+	return w.Flush()
+}
+
+// writeBackendConfig stores the options as hcl, objects (e.g. assume_role) and bools are written as such and everything else as strings,
+// the file may contain secrets, so only the owner can read it
+func writeBackendConfig(file string, options []BackendOption) error {
+	f := hclwrite.NewEmptyFile()
+	body := f.Body()
+	for _, o := range options {
+		value := cty.StringVal(o.Value)
+		if o.Value == "true" || o.Value == "false" {
+			value = cty.BoolVal(o.Value == "true")
+		} else if strings.HasPrefix(o.Value, "{") || strings.HasPrefix(o.Value, "[") {
+			expr, diags := hclsyntax.ParseExpression([]byte(o.Value), o.Key, hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				return fmt.Errorf("invalid value for %s: %w", o.Key, diags)
+			}
+			if value, diags = expr.Value(nil); diags.HasErrors() {
+				return fmt.Errorf("invalid value for %s: %w", o.Key, diags)
+			}
+		}
+		body.SetAttributeValue(o.Key, value)
+	}
+
+	return os.WriteFile(file, f.Bytes(), 0600)
+}
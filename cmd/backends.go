@@ -0,0 +1,366 @@
+// Package cmd
+/*
+Copyright © 2022 Robert Schönthal <robert@schoenthal.io>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/terrarium-tf/cli/lib"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+type s3Backend struct{}
+
+func (s3Backend) Name() string { return "s3" }
+
+func (s3Backend) RequiredVars() []string { return nil }
+
+func (s3Backend) Naming() map[string]string {
+	return map[string]string{
+		"bucket":         `tf-state-{{var "project"}}-{{var "region"}}-{{required "account"}}`,
+		"dynamodb_table": `terraform-lock-{{var "project"}}-{{var "region"}}-{{required "account"}}`,
+		"key":            `{{.Stack}}.tfstate`,
+	}
+}
+
+func (b s3Backend) Configure(cmd cobra.Command, set *lib.VarSet, stackPath string) []BackendOption {
+	opts := []BackendOption{
+		configureRegion(cmd, set),
+		configureGenerated(cmd, set, stackPath, b, "bucket", "bucket"),
+		configureStateKey(cmd, set, stackPath, b),
+	}
+
+	sl := cmd.Flags().Lookup("state-lock")
+	if sl.Value.String() == "true" {
+		// with s3 native locking the dynamo table is only used if it is configured explicitly
+		lockfile, useLockfile := varOption("use_lockfile", "use_lockfile", cmd, set, false)
+		useLockfile = useLockfile && lockfile.Value == "true"
+		if !useLockfile || backendVar("dynamo", cmd, set.Vars, false) != "" {
+			opts = append(opts,
+				configureGenerated(cmd, set, stackPath, b, "dynamodb_table", "dynamo"),
+			)
+		}
+		if useLockfile {
+			opts = append(opts, lockfile)
+		}
+	}
+
+	return append(opts, configureS3Options(cmd, set)...)
+}
+
+func configureRegion(cmd cobra.Command, set *lib.VarSet) BackendOption {
+	region, ok := varOrEnvOption("region", "region", cmd, set, "AWS_REGION", "AWS_DEFAULT_REGION")
+
+	if !ok {
+		cmd.PrintErrf(lib.ErrorColorLine, "unable to configure remote state, 'region' was not found in var files and not provided with '-state-region' nor was AWS_REGION or AWS_DEFAULT_REGION found in global environment")
+		lib.Exit(1)
+	}
+
+	return region
+}
+
+// configureS3Options passes the optional s3 settings which are set
+func configureS3Options(cmd cobra.Command, set *lib.VarSet) []BackendOption {
+	// AWS_PROFILE and the AWS_ENDPOINT_URL_* environment variables are read by terraform itself
+	opts := optionalBackendConfigs(cmd, set, "encrypt", "kms_key_id", "workspace_key_prefix", "profile")
+
+	if role, ok := configureAssumeRole(cmd, set); ok {
+		opts = append(opts, role)
+	}
+	if endpoints, ok := configureS3Endpoints(cmd, set); ok {
+		opts = append(opts, endpoints)
+	}
+
+	// mostly needed for s3 compatible storages like MinIO or LocalStack
+	return append(opts, optionalBackendConfigs(cmd, set,
+		"use_path_style",
+		"skip_credentials_validation",
+		"skip_region_validation",
+		"skip_requesting_account_id",
+		"skip_metadata_api_check",
+		"skip_s3_checksum",
+	)...)
+}
+
+// configureAssumeRole uses an "assume_role" object or builds it from role_arn, session_name and external_id,
+// objects are passed as json which terraform parses as hcl
+func configureAssumeRole(cmd cobra.Command, set *lib.VarSet) (BackendOption, bool) {
+	if role, ok := varOption("assume_role", "assume_role", cmd, set, false); ok && strings.HasPrefix(role.Value, "{") {
+		return role, true
+	}
+
+	role := make(map[string]any)
+	var sources, vars []string
+	for _, name := range []string{"role_arn", "session_name", "external_id"} {
+		if o, ok := varOption(name, name, cmd, set, false); ok {
+			role[name] = o.Value
+			sources = appendUnique(sources, o.Source)
+			vars = append(vars, o.Vars...)
+		}
+	}
+	if _, ok := role["role_arn"]; !ok {
+		return BackendOption{}, false
+	}
+	return BackendOption{Key: "assume_role", Value: lib.VarToString(role), Source: strings.Join(sources, ", "), Vars: vars}, true
+}
+
+// configureS3Endpoints uses an "endpoints" object
+func configureS3Endpoints(cmd cobra.Command, set *lib.VarSet) (BackendOption, bool) {
+	if endpoints, ok := varOption("endpoints", "endpoints", cmd, set, false); ok && strings.HasPrefix(endpoints.Value, "{") {
+		return endpoints, true
+	}
+	return BackendOption{}, false
+}
+
+type gcsBackend struct{}
+
+func (gcsBackend) Name() string { return "gcs" }
+
+func (gcsBackend) RequiredVars() []string { return nil }
+
+func (gcsBackend) Naming() map[string]string {
+	return map[string]string{
+		"bucket": `tf-state-{{var "project"}}`,
+		"prefix": `{{.Stack}}`,
+	}
+}
+
+func (b gcsBackend) Configure(cmd cobra.Command, set *lib.VarSet, stackPath string) []BackendOption {
+	return []BackendOption{
+		configureGcpCredentials(cmd, set),
+		configureGenerated(cmd, set, stackPath, b, "bucket", "bucket"),
+		configureGenerated(cmd, set, stackPath, b, "prefix", "prefix"),
+	}
+}
+
+func configureGcpCredentials(cmd cobra.Command, set *lib.VarSet) BackendOption {
+	file, ok := varOrEnvOption("credentials", "credentials", cmd, set, "GOOGLE_BACKEND_CREDENTIALS", "GOOGLE_CREDENTIALS")
+
+	if !ok {
+		cmd.PrintErrf(lib.ErrorColorLine, "unable to configure remote state, 'credentials' was not found in var files and not provided with '-state-credentials' nor was GOOGLE_BACKEND_CREDENTIALS or GOOGLE_CREDENTIALS found in global environment")
+		lib.Exit(1)
+	}
+
+	return file
+}
+
+type azurermBackend struct{}
+
+func (azurermBackend) Name() string { return "azurerm" }
+
+func (azurermBackend) RequiredVars() []string { return []string{"account", "project"} }
+
+func (azurermBackend) Naming() map[string]string {
+	return map[string]string{
+		"container_name": `tf-state-{{var "project"}}-{{required "account"}}`,
+		"key":            `{{.Stack}}.tfstate`,
+	}
+}
+
+func (b azurermBackend) Configure(cmd cobra.Command, set *lib.VarSet, stackPath string) []BackendOption {
+	account, _ := varOption("storage_account_name", "account", cmd, set, true)
+	group, _ := varOption("resource_group_name", "project", cmd, set, true)
+
+	opts := []BackendOption{
+		account,
+		group,
+		configureStateKey(cmd, set, stackPath, b),
+		configureGenerated(cmd, set, stackPath, b, "container_name", "bucket"),
+	}
+
+	return append(opts, configureAzureFromEnv(cmd, set)...)
+}
+
+func configureAzureFromEnv(cmd cobra.Command, set *lib.VarSet) []BackendOption {
+	var opts []BackendOption
+
+	vars := [][]string{
+		{"environment", "ARM_ENVIRONMENT"},
+		{"endpoint", "ARM_ENDPOINT"},
+		{"metadata_host", "ARM_METADATA_HOSTNAME"},
+		{"snapshot", "ARM_SNAPSHOT"},
+		{"msi_endpoint", "ARM_MSI_ENDPOINT"},
+		{"use_msi", "ARM_USE_MSI"},
+		{"oidc_request_url", "ARM_OIDC_REQUEST_URL"},
+		{"oidc_request_token", "ARM_OIDC_REQUEST_TOKEN"},
+		{"oidc_token", "ARM_OIDC_TOKEN"},
+		{"oidc_token_file_path", "ARM_OIDC_TOKEN_FILE_PATH"},
+		{"use_oidc", "ARM_USE_OIDC"},
+		{"sas_token", "ARM_SAS_TOKEN"},
+		{"access_key", "ARM_ACCESS_KEY"},
+		{"use_azuread_auth", "ARM_USE_AZUREAD"},
+		{"client_id", "ARM_CLIENT_ID"},
+		{"client_certificate_password", "ARM_CLIENT_CERTIFICATE_PASSWORD"},
+		{"client_certificate_path", "ARM_CLIENT_CERTIFICATE_PATH"},
+		{"client_secret", "ARM_CLIENT_SECRET"},
+		{"subscription_id", "ARM_SUBSCRIPTION_ID"},
+		{"tenant_id", "ARM_TENANT_ID"},
+	}
+
+	for _, tuple := range vars {
+		if o, ok := varOrEnvOption(tuple[0], tuple[0], cmd, set, tuple[1]); ok {
+			opts = append(opts, o)
+		}
+	}
+
+	return opts
+}
+
+// pgBackend stores the states of a stack in its own schema, the workspaces share it
+type pgBackend struct{}
+
+func (pgBackend) Name() string { return "pg" }
+
+func (pgBackend) RequiredVars() []string { return nil }
+
+func (pgBackend) Naming() map[string]string {
+	return map[string]string{
+		"schema_name": `tf_state_{{var "project"}}_{{.Stack}}`,
+	}
+}
+
+var pgInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+func (b pgBackend) Configure(cmd cobra.Command, set *lib.VarSet, stackPath string) []BackendOption {
+	var opts []BackendOption
+
+	// terraform reads PG_CONN_STR itself, so the connection string is only passed if it is a variable
+	if conn, ok := varOption("conn_str", "conn_str", cmd, set, false); ok {
+		opts = append(opts, conn)
+	} else if os.Getenv("PG_CONN_STR") == "" {
+		cmd.PrintErrf(lib.ErrorColorLine, "unable to configure remote state, 'conn_str' was not found in var files nor was PG_CONN_STR found in global environment")
+		lib.Exit(1)
+	}
+
+	schema, ok := varOption("schema_name", "schema_name", cmd, set, false)
+	if !ok {
+		// no schema defined, so generate a valid unique name
+		schema = generatedOption(cmd, set, stackPath, b, "schema_name")
+		schema.Value = strings.Trim(pgInvalidChars.ReplaceAllString(strings.ToLower(schema.Value), "_"), "_")
+	}
+
+	return append(opts, schema)
+}
+
+type consulBackend struct{}
+
+func (consulBackend) Name() string { return "consul" }
+
+func (consulBackend) RequiredVars() []string { return nil }
+
+func (consulBackend) Naming() map[string]string {
+	return map[string]string{
+		"path": `terraform/{{var "project"}}/{{.Stack}}`,
+	}
+}
+
+func (b consulBackend) Configure(cmd cobra.Command, set *lib.VarSet, stackPath string) []BackendOption {
+	key, ok := varOption("path", "path", cmd, set, false)
+	if !ok {
+		// no path defined, so generate a unique name
+		key = generatedOption(cmd, set, stackPath, b, "path")
+		key.Value = path.Clean(key.Value)
+	}
+
+	// the address and token default to CONSUL_HTTP_ADDR and CONSUL_HTTP_TOKEN in terraform
+	opts := append([]BackendOption{key}, optionalBackendConfigs(cmd, set, "address", "scheme", "datacenter", "access_token")...)
+
+	sl := cmd.Flags().Lookup("state-lock")
+	if sl.Value.String() != "true" {
+		opts = append(opts, BackendOption{Key: "lock", Value: "false", Source: "flag --state-lock"})
+	}
+	return opts
+}
+
+type httpBackend struct{}
+
+func (httpBackend) Name() string { return "http" }
+
+func (httpBackend) RequiredVars() []string { return []string{"address"} }
+
+// Naming is empty, the address of the state has to be configured
+func (httpBackend) Naming() map[string]string { return nil }
+
+func (httpBackend) Configure(cmd cobra.Command, set *lib.VarSet, _ string) []BackendOption {
+	opts := optionalBackendConfigs(cmd, set, "address", "update_method", "username", "password", "skip_cert_verification")
+
+	sl := cmd.Flags().Lookup("state-lock")
+	if sl.Value.String() == "true" {
+		opts = append(opts, optionalBackendConfigs(cmd, set, "lock_address", "lock_method", "unlock_address", "unlock_method")...)
+	}
+	return opts
+}
+
+// kubernetesBackend stores the states in secrets named by the workspace and the suffix
+type kubernetesBackend struct{}
+
+func (kubernetesBackend) Name() string { return "kubernetes" }
+
+func (kubernetesBackend) RequiredVars() []string { return nil }
+
+func (kubernetesBackend) Naming() map[string]string {
+	return map[string]string{
+		"secret_suffix": `{{var "project"}}-{{.Stack}}`,
+	}
+}
+
+var kubernetesInvalidChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+func (b kubernetesBackend) Configure(cmd cobra.Command, set *lib.VarSet, stackPath string) []BackendOption {
+	suffix, ok := varOption("secret_suffix", "secret_suffix", cmd, set, false)
+	if !ok {
+		// no suffix defined, so generate a valid unique name
+		suffix = generatedOption(cmd, set, stackPath, b, "secret_suffix")
+		suffix.Value = strings.Trim(kubernetesInvalidChars.ReplaceAllString(strings.ToLower(suffix.Value), "-"), "-")
+	}
+
+	return append([]BackendOption{suffix}, optionalBackendConfigs(cmd, set, "namespace", "config_path", "config_context")...)
+}
+
+// localBackend keeps the state in the stack directory unless a path is configured
+type localBackend struct{}
+
+func (localBackend) Name() string { return "local" }
+
+func (localBackend) RequiredVars() []string { return nil }
+
+// Naming is empty, terraform uses terraform.tfstate in the stack directory
+func (localBackend) Naming() map[string]string { return nil }
+
+func (localBackend) Configure(cmd cobra.Command, set *lib.VarSet, _ string) []BackendOption {
+	return optionalBackendConfigs(cmd, set, "path", "workspace_dir")
+}
+
+// cloudBackend is a "cloud" block, terraform doesnt accept -backend-config for it,
+// it is configured in the block or with the TF_CLOUD_* environment variables
+type cloudBackend struct{}
+
+func (cloudBackend) Name() string { return lib.CloudBackend }
+
+func (cloudBackend) RequiredVars() []string { return nil }
+
+func (cloudBackend) Naming() map[string]string { return nil }
+
+func (cloudBackend) Configure(cobra.Command, *lib.VarSet, string) []BackendOption { return nil }
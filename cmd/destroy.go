@@ -23,7 +23,6 @@ THE SOFTWARE.
 package cmd
 
 import (
-	"fmt"
 	"github.com/hashicorp/terraform-exec/tfexec"
 	"github.com/spf13/cobra"
 	"github.com/terrarium-tf/cli/lib"
@@ -36,21 +35,28 @@ func NewDestroyCommand(root *cobra.Command) {
 		Args:  lib.ArgsValidator,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
-			tf, ctx, files, _ := lib.Executor(*cmd, args[0], args[1], true)
+			tf, ctx, set := lib.Executor(*cmd, args[0], args[1], true)
 
-			return tf.Destroy(ctx, buildDestroyOptions(files, args)...)
+			return tf.Destroy(ctx, buildDestroyOptions(set)...)
 		},
 	}
 
+	destroyCmd.Flags().Bool("strict", false, "fail on warnings of the var validation")
+	addOverrideFlags(destroyCmd)
+
 	root.AddCommand(destroyCmd)
 }
 
-func buildDestroyOptions(files []string, args []string) []tfexec.DestroyOption {
+func buildDestroyOptions(set *lib.VarSet) []tfexec.DestroyOption {
 	var ops []tfexec.DestroyOption
 
-	for _, f := range files {
+	for _, f := range set.Files {
 		ops = append(ops, tfexec.VarFile(f))
 	}
 
-	return append(ops, tfexec.Var(fmt.Sprintf("environment=%s", args[0])))
+	for _, v := range set.InjectedVars() {
+		ops = append(ops, tfexec.Var(v))
+	}
+
+	return ops
 }
@@ -24,7 +24,6 @@ package cmd
 
 import (
 	"errors"
-	"fmt"
 	"github.com/hashicorp/terraform-exec/tfexec"
 	"github.com/spf13/cobra"
 	"github.com/terrarium-tf/cli/lib"
@@ -37,12 +36,15 @@ func NewImportCommand(root *cobra.Command) {
 		Example: "import prod path/to/stack aws_s3_bucket.example some_aws_bucket_name",
 		Args:    importArgsValidator,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			tf, ctx, files, _ := lib.Executor(*cmd, args[0], args[1], true)
+			tf, ctx, set := lib.Executor(*cmd, args[0], args[1], true)
 
-			return tf.Import(ctx, args[2], args[3], buildImportOptions(files, args)...)
+			return tf.Import(ctx, args[2], args[3], buildImportOptions(set)...)
 		},
 	}
 
+	importCmd.Flags().Bool("strict", false, "fail on warnings of the var validation")
+	addOverrideFlags(importCmd)
+
 	root.AddCommand(importCmd)
 }
 
@@ -54,12 +56,16 @@ func importArgsValidator(cmd *cobra.Command, args []string) error {
 	return lib.ArgsValidator(cmd, args)
 }
 
-func buildImportOptions(files []string, args []string) []tfexec.ImportOption {
+func buildImportOptions(set *lib.VarSet) []tfexec.ImportOption {
 	var ops []tfexec.ImportOption
 
-	for _, f := range files {
+	for _, f := range set.Files {
 		ops = append(ops, tfexec.VarFile(f))
 	}
 
-	return append(ops, tfexec.Var(fmt.Sprintf("environment=%s", args[0])))
+	for _, v := range set.InjectedVars() {
+		ops = append(ops, tfexec.Var(v))
+	}
+
+	return ops
 }
@@ -38,15 +38,15 @@ func NewPlanCommand(root *cobra.Command) {
 		Args:  lib.ArgsValidator,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
-			tf, ctx, files, _ := lib.Executor(*cmd, args[0], args[1], true)
+			tf, ctx, set := lib.Executor(*cmd, args[0], args[1], true)
 
 			//plan
 			planFile := ""
 			if os.Getenv("TF_IN_AUTOMATION") != "" {
-				planFile = fmt.Sprintf("%s-%s.tfplan", strings.Replace(time.Now().Format(time.RFC3339), ":", "-", -1), args[0])
+				planFile = fmt.Sprintf("%s-%s.tfplan", strings.Replace(time.Now().Format(time.RFC3339), ":", "-", -1), set.Workspace)
 			}
 
-			diff, err := tf.Plan(ctx, buildPlanOptions(files, args, planFile)...)
+			diff, err := tf.Plan(ctx, buildPlanOptions(set, planFile)...)
 
 			// behave exactly like terraform:
 			/*
@@ -55,12 +55,15 @@ func NewPlanCommand(root *cobra.Command) {
 				2 = Succeeded with non-empty diff (changes present)
 			*/
 			if diff {
-				os.Exit(2)
+				lib.Exit(2)
 			}
 
 			return err
 		},
 	}
 
+	planCmd.Flags().Bool("strict", false, "fail on warnings of the var validation")
+	addOverrideFlags(planCmd)
+
 	root.AddCommand(planCmd)
 }
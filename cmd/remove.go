@@ -35,7 +35,7 @@ func NewRemoveCommand(root *cobra.Command) {
 		Example: "remove prod path/to/stack aws_s3_bucket.example",
 		Args:    removeArgsValidator,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			tf, ctx, _, _ := lib.Executor(*cmd, args[0], args[1], true)
+			tf, ctx, _ := lib.Executor(*cmd, args[0], args[1], true)
 
 			return tf.StateRm(ctx, args[2])
 		},
@@ -29,7 +29,10 @@ import (
 )
 
 func Execute(command *cobra.Command) {
+	stop := lib.CleanupOnSignal()
 	err := command.Execute()
+	stop()
+	lib.Cleanup()
 	if err != nil {
 		os.Exit(1)
 	}
@@ -55,12 +58,14 @@ Add "-v" for more verbose logging.
 
 	rootCmd.PersistentFlags().StringVarP(&binary, "terraform", "t", lib.Binary(), "terraform binary found in your path")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "display extended informations")
+	rootCmd.PersistentFlags().String("root", "", "project root, var files are never searched above it (default: nearest directory with a .terrarium.yaml or .git)")
 
 	return rootCmd
 }
 
 func AddChildCommands(rootCmd *cobra.Command) {
 	NewApplyCommand(rootCmd)
+	NewBackendCommand(rootCmd)
 	NewDestroyCommand(rootCmd)
 	NewImportCommand(rootCmd)
 	NewInitCommand(rootCmd)
@@ -68,4 +73,11 @@ func AddChildCommands(rootCmd *cobra.Command) {
 	NewRemoveCommand(rootCmd)
 	NewUntaintCommand(rootCmd)
 	NewTaintCommand(rootCmd)
+	NewVarsCommand(rootCmd)
+}
+
+// addOverrideFlags adds --var and --var-file, which override all collected var files
+func addOverrideFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray("var", nil, "set a variable as key=value, overrides all var files (can be repeated)")
+	cmd.Flags().StringArray("var-file", nil, "additional var file, overrides all collected var files (can be repeated)")
 }
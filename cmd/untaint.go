@@ -35,7 +35,7 @@ func NewUntaintCommand(root *cobra.Command) {
 		Args:  untaintArgsValidator,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
-			tf, ctx, _, _ := lib.Executor(*cmd, args[0], args[1], true)
+			tf, ctx, _ := lib.Executor(*cmd, args[0], args[1], true)
 
 			return tf.Untaint(ctx, args[2])
 		},
@@ -0,0 +1,122 @@
+// Package cmd
+/*
+Copyright © 2022 Robert Schönthal <robert@schoenthal.io>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/terrarium-tf/cli/lib"
+	"text/tabwriter"
+)
+
+type varOutput struct {
+	Name     string           `json:"name"`
+	Value    any              `json:"value"`
+	Layer    string           `json:"layer"`
+	File     string           `json:"file"`
+	Shadowed []lib.Assignment `json:"shadowed"`
+}
+
+func NewVarsCommand(root *cobra.Command) {
+	var varsCmd = &cobra.Command{
+		Use:   "vars workspace path/to/stack [--output=table|json]",
+		Short: "Prints the effective variables of a stack and where they come from",
+		Long: `Resolves all var files of the given workspace and stack (without running terraform)
+and prints every variable with its final value, the file which set it and the values it shadowed.`,
+		Example: "vars prod path/to/stack --output=json",
+		Args:    lib.ArgsValidator,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			set, err := lib.CollectVars(args[0], args[1], lib.CliOverrides(*cmd))
+			if err != nil {
+				return err
+			}
+
+			output, _ := cmd.Flags().GetString("output")
+			switch output {
+			case "json":
+				return printVarsJson(cmd, set)
+			case "table":
+				return printVarsTable(cmd, set)
+			default:
+				return fmt.Errorf("unknown output format '%s', use table or json", output)
+			}
+		},
+	}
+
+	varsCmd.Flags().StringP("output", "o", "table", "output format: table or json")
+	addOverrideFlags(varsCmd)
+	NewVarsValidateCommand(varsCmd)
+	NewVarsDiffCommand(varsCmd)
+	NewVarsSetCommand(varsCmd)
+	NewVarsUnsetCommand(varsCmd)
+
+	root.AddCommand(varsCmd)
+}
+
+func buildVarsOutput(set *lib.VarSet) []varOutput {
+	out := make([]varOutput, 0, len(set.Vars))
+	for _, k := range set.SortedKeys() {
+		var sources []lib.Assignment
+		for _, s := range set.Sources[k] {
+			s.Value = set.Redactor.RedactValue(k, s.Value)
+			sources = append(sources, s)
+		}
+		last := sources[len(sources)-1]
+
+		// list the shadowed values, the most recent first
+		shadowed := make([]lib.Assignment, 0, len(sources)-1)
+		for i := len(sources) - 2; i >= 0; i-- {
+			shadowed = append(shadowed, sources[i])
+		}
+
+		// the effective value might differ from the last assignment (deep merge, interpolation)
+		value := set.Redactor.RedactValue(k, set.Vars[k])
+
+		out = append(out, varOutput{Name: k, Value: value, Layer: last.Layer, File: last.File, Shadowed: shadowed})
+	}
+	return out
+}
+
+func printVarsJson(cmd *cobra.Command, set *lib.VarSet) error {
+	content, err := json.MarshalIndent(buildVarsOutput(set), "", "  ")
+	if err != nil {
+		return err
+	}
+	cmd.Println(string(content))
+	return nil
+}
+
+func printVarsTable(cmd *cobra.Command, set *lib.VarSet) error {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tVALUE\tLAYER\tFILE")
+
+	for _, v := range buildVarsOutput(set) {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.Name, lib.VarToString(v.Value), v.Layer, v.File)
+		for _, s := range v.Shadowed {
+			_, _ = fmt.Fprintf(w, "\t  shadows %s\t%s\t%s\n", lib.VarToString(s.Value), s.Layer, s.File)
+		}
+	}
+
+	return w.Flush()
+}
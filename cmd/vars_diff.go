@@ -0,0 +1,180 @@
+// Package cmd
+/*
+Copyright © 2022 Robert Schönthal <robert@schoenthal.io>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/terrarium-tf/cli/lib"
+	"os"
+	"reflect"
+	"sort"
+	"text/tabwriter"
+)
+
+const (
+	diffAdded   = "added"
+	diffRemoved = "removed"
+	diffChanged = "changed"
+)
+
+// varDiff is a variable which differs between two workspaces, From or To is nil if it is missing there
+type varDiff struct {
+	Name   string          `json:"name"`
+	Change string          `json:"change"`
+	From   *lib.Assignment `json:"from,omitempty"`
+	To     *lib.Assignment `json:"to,omitempty"`
+}
+
+func NewVarsDiffCommand(vars *cobra.Command) {
+	var diffCmd = &cobra.Command{
+		Use:   "diff workspace other-workspace path/to/stack [--output=table|json]",
+		Short: "Compares the effective variables of a stack between two workspaces",
+		Long: `Resolves all var files of the stack for both workspaces (including global and inherited files)
+and prints the added, removed and changed variables with the file which set each side.`,
+		Example: "vars diff stage prod path/to/stack --output=json",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 3 {
+				return errors.New("requires two workspaces and a stack path")
+			}
+			if _, err := os.Stat(args[2]); os.IsNotExist(err) {
+				return fmt.Errorf("invalid path given: %s", args[2])
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cli := lib.Overrides{Root: lib.CliRoot(*cmd)}
+			from, err := lib.CollectVars(args[0], args[2], cli)
+			if err != nil {
+				return err
+			}
+			to, err := lib.CollectVars(args[1], args[2], cli)
+			if err != nil {
+				return err
+			}
+
+			diff := buildVarsDiff(from, to)
+
+			output, _ := cmd.Flags().GetString("output")
+			switch output {
+			case "json":
+				content, err := json.MarshalIndent(diff, "", "  ")
+				if err != nil {
+					return err
+				}
+				cmd.Println(string(content))
+				return nil
+			case "table":
+				return printVarsDiffTable(cmd, from.Workspace, to.Workspace, diff)
+			default:
+				return fmt.Errorf("unknown output format '%s', use table or json", output)
+			}
+		},
+	}
+
+	diffCmd.Flags().StringP("output", "o", "table", "output format: table or json")
+
+	vars.AddCommand(diffCmd)
+}
+
+func buildVarsDiff(from *lib.VarSet, to *lib.VarSet) []varDiff {
+	names := from.SortedKeys()
+	for _, k := range to.SortedKeys() {
+		if _, ok := from.Vars[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	diff := make([]varDiff, 0)
+	for _, k := range names {
+		// injected values like the workspace name differ by design
+		if isInjected(from, k) && isInjected(to, k) {
+			continue
+		}
+
+		a, inFrom := from.Vars[k]
+		b, inTo := to.Vars[k]
+
+		d := varDiff{Name: k}
+		switch {
+		case !inFrom:
+			d.Change = diffAdded
+		case !inTo:
+			d.Change = diffRemoved
+		case !reflect.DeepEqual(a, b):
+			d.Change = diffChanged
+		default:
+			continue
+		}
+
+		if inFrom {
+			d.From = effectiveAssignment(from, k)
+		}
+		if inTo {
+			d.To = effectiveAssignment(to, k)
+		}
+		diff = append(diff, d)
+	}
+	return diff
+}
+
+func isInjected(set *lib.VarSet, name string) bool {
+	sources := set.Sources[name]
+	return len(sources) > 0 && sources[len(sources)-1].Layer == lib.InjectLayer
+}
+
+// effectiveAssignment is the final value of a variable and the file which set it, sensitive values are redacted
+func effectiveAssignment(set *lib.VarSet, name string) *lib.Assignment {
+	sources := set.Sources[name]
+	a := sources[len(sources)-1]
+
+	a.Value = set.Redactor.RedactValue(name, set.Vars[name])
+	return &a
+}
+
+func printVarsDiffTable(cmd *cobra.Command, from string, to string, diff []varDiff) error {
+	if len(diff) == 0 {
+		cmd.Printf("no differences between %s and %s\n", from, to)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "\tNAME\t%s\t%s\n", from, to)
+
+	side := func(a *lib.Assignment) string {
+		if a == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%s (%s: %s)", lib.VarToString(a.Value), a.Layer, a.File)
+	}
+	markers := map[string]string{diffAdded: "+", diffRemoved: "-", diffChanged: "~"}
+
+	for _, d := range diff {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", markers[d.Change], d.Name, side(d.From), side(d.To))
+	}
+
+	return w.Flush()
+}
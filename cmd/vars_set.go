@@ -0,0 +1,145 @@
+// Package cmd
+/*
+Copyright © 2022 Robert Schönthal <robert@schoenthal.io>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/terrarium-tf/cli/lib"
+	"os"
+	"strings"
+)
+
+func NewVarsSetCommand(vars *cobra.Command) {
+	var setCmd = &cobra.Command{
+		Use:   "set workspace path/to/stack key=value... [--layer=env] [--json]",
+		Short: "Sets variables in the var file of a layer",
+		Long: `Finds the json var file of the layer with the same rules as all other commands and sets the variables,
+the file is created if missing. The order of the keys and the formatting of all other values are kept.
+Values are strings, with --json they are parsed as json, e.g. for numbers, lists and objects.`,
+		Example: `vars set prod path/to/stack image_tag=1.2.3
+vars set prod path/to/stack 'subnets=["10.0.1.0/24"]' --json --layer app`,
+		Args: varsEditArgs("requires a workspace, a stack path and at least one key=value"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			asJson, _ := cmd.Flags().GetBool("json")
+
+			values := make([]lib.VarValue, 0, len(args)-2)
+			for _, arg := range args[2:] {
+				key, value, ok := strings.Cut(arg, "=")
+				key = strings.TrimSpace(key)
+				if !ok || key == "" {
+					return fmt.Errorf("invalid assignment '%s', expected key=value", arg)
+				}
+
+				v := lib.VarValue{Name: key, Value: value}
+				if asJson {
+					dec := json.NewDecoder(bytes.NewReader([]byte(value)))
+					dec.UseNumber()
+					if err := dec.Decode(&v.Value); err != nil {
+						return fmt.Errorf("invalid json value for %s: %w", key, err)
+					}
+				}
+				values = append(values, v)
+			}
+
+			file, _, err := varsEditFile(cmd, args)
+			if err != nil {
+				return err
+			}
+			if _, err := lib.EditVarsFile(file, values, nil); err != nil {
+				return err
+			}
+
+			for _, v := range values {
+				cmd.Printf("set %s in %s\n", v.Name, file)
+			}
+			return nil
+		},
+	}
+
+	setCmd.Flags().String("layer", "env", "layer of the var file, e.g. global, local, app or env")
+	setCmd.Flags().Bool("json", false, "parse the values as json")
+
+	vars.AddCommand(setCmd)
+}
+
+func NewVarsUnsetCommand(vars *cobra.Command) {
+	var unsetCmd = &cobra.Command{
+		Use:   "unset workspace path/to/stack key... [--layer=env]",
+		Short: "Removes variables from the var file of a layer",
+		Long: `Finds the json var file of the layer with the same rules as all other commands and removes the variables,
+the order of the keys and the formatting of all other values are kept.`,
+		Example: "vars unset prod path/to/stack image_tag --layer app",
+		Args:    varsEditArgs("requires a workspace, a stack path and at least one key"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, exists, err := varsEditFile(cmd, args)
+			if err != nil {
+				return err
+			}
+
+			var removed []string
+			if exists {
+				if removed, err = lib.EditVarsFile(file, nil, args[2:]); err != nil {
+					return err
+				}
+			}
+
+			if len(removed) == 0 {
+				cmd.Printf("nothing to remove in %s\n", file)
+			} else {
+				cmd.Printf("removed %s from %s\n", strings.Join(removed, ", "), file)
+			}
+			return nil
+		},
+	}
+
+	unsetCmd.Flags().String("layer", "env", "layer of the var file, e.g. global, local, app or env")
+
+	vars.AddCommand(unsetCmd)
+}
+
+func varsEditArgs(message string) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if len(args) < 3 {
+			return errors.New(message)
+		}
+		if _, err := os.Stat(args[1]); os.IsNotExist(err) {
+			return fmt.Errorf("invalid path given: %s", args[1])
+		}
+		return nil
+	}
+}
+
+// varsEditFile finds the var file of the --layer for the workspace and stack
+func varsEditFile(cmd *cobra.Command, args []string) (string, bool, error) {
+	project, err := lib.LoadProject(args[1], lib.CliRoot(*cmd))
+	if err != nil {
+		return "", false, err
+	}
+
+	layer, _ := cmd.Flags().GetString("layer")
+	return project.LayerFile(layer, args[0], args[1])
+}
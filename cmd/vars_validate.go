@@ -0,0 +1,119 @@
+// Package cmd
+/*
+Copyright © 2022 Robert Schönthal <robert@schoenthal.io>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/terrarium-tf/cli/lib"
+)
+
+func NewVarsValidateCommand(vars *cobra.Command) {
+	var validateCmd = &cobra.Command{
+		Use:   "validate [path/to/stack...]",
+		Short: "Validates the var files of all stacks and workspaces against the project's JSON Schemas",
+		Long: `Validates every var file of the given stacks (default: all stacks of the project) in every workspace
+against the schemas configured in .terrarium.yaml, every violation is reported with file, JSON pointer and message.
+Workspaces are discovered from the var files, use --workspace to check specific ones.`,
+		Example:      "vars validate path/to/stack -w prod",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stacks := args
+			if len(stacks) == 0 {
+				project, err := lib.LoadProject(".", lib.CliRoot(*cmd))
+				if err != nil {
+					return err
+				}
+				if stacks, err = project.DiscoverStacks(); err != nil {
+					return err
+				}
+			}
+
+			workspaces, _ := cmd.Flags().GetStringArray("workspace")
+			verbose, _ := cmd.Flags().GetBool("verbose")
+
+			var errs []string
+			checked := 0
+			for _, stack := range stacks {
+				project, err := lib.LoadProject(stack, lib.CliRoot(*cmd))
+				if err != nil {
+					return err
+				}
+
+				stackWorkspaces := workspaces
+				if len(stackWorkspaces) == 0 {
+					if stackWorkspaces, err = project.DiscoverWorkspaces(stack); err != nil {
+						return err
+					}
+				}
+				if len(stackWorkspaces) == 0 {
+					stackWorkspaces = []string{"default"}
+				}
+
+				for _, workspace := range stackWorkspaces {
+					if verbose {
+						cmd.Printf(lib.InfoColorLine, fmt.Sprintf("%s (%s)", stack, workspace))
+					}
+					checked++
+
+					set, err := lib.CollectVars(workspace, stack, lib.Overrides{Root: lib.CliRoot(*cmd)})
+					if err != nil {
+						errs = appendUnique(errs, fmt.Sprintf("%s (%s): %s", stack, workspace, err.Error()))
+						continue
+					}
+
+					found, err := set.ValidateSchema()
+					if err != nil {
+						return err
+					}
+					for _, e := range found {
+						errs = appendUnique(errs, e.String())
+					}
+				}
+			}
+
+			for _, e := range errs {
+				cmd.PrintErrf(lib.ErrorColorLine, e)
+			}
+			if len(errs) > 0 {
+				return fmt.Errorf("%d problem(s) found in %d stack workspace(s)", len(errs), checked)
+			}
+
+			cmd.Printf(lib.InfoColorLine, fmt.Sprintf("%d stack workspace(s) are valid", checked))
+			return nil
+		},
+	}
+
+	validateCmd.Flags().StringArrayP("workspace", "w", nil, "workspace to validate (can be repeated), default: all workspaces with var files")
+
+	vars.AddCommand(validateCmd)
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, e := range list {
+		if e == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
@@ -0,0 +1,11 @@
+variable "environment" {}
+
+terraform {
+  cloud {
+    organization = "terrarium"
+
+    workspaces {
+      tags = ["cloud"]
+    }
+  }
+}
@@ -0,0 +1,12 @@
+variable "environment" {}
+
+# backend "gcs" {}
+terraform {
+  # backend "s3" {}
+  backend "consul" {
+  }
+}
+
+module "vendored" {
+  source = "./modules/vendored"
+}
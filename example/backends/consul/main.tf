@@ -0,0 +1,6 @@
+variable "environment" {}
+
+terraform {
+  backend "consul" {
+  }
+}
@@ -0,0 +1,17 @@
+variable "region" {}
+variable "environment" {}
+variable "project" {}
+variable "account" {}
+variable "stack" {}
+variable "foo" {
+  type = bool
+}
+
+resource "aws_s3_bucket" "test" {
+  bucket = "test-${var.environment}-${var.stack}"
+}
+
+terraform {
+  backend "s3" {
+  }
+}
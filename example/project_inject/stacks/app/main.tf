@@ -0,0 +1,12 @@
+variable "env" {}
+variable "stack_name" {}
+variable "deployed_by" {}
+variable "revision" {}
+
+resource "null_resource" "app" {
+  triggers = {
+    name     = "${var.stack_name}-${var.env}"
+    owner    = var.deployed_by
+    revision = var.revision
+  }
+}
@@ -0,0 +1,12 @@
+variable "environment" {}
+variable "env" {}
+variable "deployed_by" {
+  default = "nobody"
+}
+
+resource "null_resource" "legacy" {
+  triggers = {
+    name  = "legacy-${var.environment}-${var.env}"
+    owner = var.deployed_by
+  }
+}
@@ -0,0 +1,4 @@
+variable "environment" {}
+variable "project" {}
+variable "region" {}
+variable "account" {}
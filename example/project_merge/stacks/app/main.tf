@@ -0,0 +1,24 @@
+variable "region" {}
+variable "environment" {}
+variable "project" {}
+variable "account" {}
+variable "stack" {}
+variable "tags" {
+  type = map(string)
+}
+variable "subnets" {
+  type = list(string)
+}
+variable "dns" {
+  type = object({ zone = string, ttl = optional(number) })
+}
+
+resource "aws_s3_bucket" "test" {
+  bucket = "test-${var.environment}-${var.stack}"
+  tags   = var.tags
+}
+
+terraform {
+  backend "s3" {
+  }
+}
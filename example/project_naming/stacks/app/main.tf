@@ -0,0 +1,11 @@
+variable "environment" {}
+variable "org" {}
+variable "team" {}
+variable "project" {}
+variable "account" {}
+variable "region" {}
+
+terraform {
+  backend "s3" {
+  }
+}
@@ -0,0 +1,9 @@
+variable "project" {}
+variable "region" {}
+variable "replicas" {
+  type = number
+}
+variable "tags" {
+  type    = map(string)
+  default = {}
+}
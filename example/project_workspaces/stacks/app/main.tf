@@ -0,0 +1,17 @@
+variable "environment" {}
+variable "region" {}
+variable "replicas" {
+  type = number
+}
+
+resource "null_resource" "app" {
+  count = var.replicas
+  triggers = {
+    name = "app-${var.environment}-${var.region}"
+  }
+}
+
+variable "failover" {
+  type    = bool
+  default = false
+}
@@ -0,0 +1,21 @@
+variable "region" {}
+variable "environment" {}
+variable "project" {}
+variable "account" {}
+variable "stack" {}
+variable "foo" {
+  type = bool
+}
+variable "tags" {
+  type = map(string)
+}
+
+resource "aws_s3_bucket" "test" {
+  bucket = "test-${var.environment}-${var.stack}"
+  tags   = var.tags
+}
+
+terraform {
+  backend "s3" {
+  }
+}
@@ -0,0 +1,17 @@
+variable "environment" {}
+variable "project" {}
+variable "region" {}
+variable "account" {}
+variable "stack" {}
+variable "cidr" {}
+variable "subnets" {
+  type = list(string)
+}
+variable "tags" {
+  type = map(string)
+}
+
+resource "aws_vpc" "main" {
+  cidr_block = var.cidr
+  tags       = var.tags
+}
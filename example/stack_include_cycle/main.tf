@@ -0,0 +1,2 @@
+variable "stack" {}
+variable "region" {}
@@ -0,0 +1,19 @@
+variable "region" {}
+variable "environment" {}
+variable "project" {}
+variable "account" {}
+variable "stack" {}
+variable "db_password" {}
+variable "db" {}
+variable "db_endpoint" {
+  sensitive = true
+}
+
+resource "aws_s3_bucket" "test" {
+  bucket = "test-${var.environment}-${var.stack}"
+}
+
+terraform {
+  backend "s3" {
+  }
+}
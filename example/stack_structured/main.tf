@@ -0,0 +1,26 @@
+variable "region" {}
+variable "environment" {}
+variable "project" {}
+variable "account" {}
+variable "backend" {
+  type = object({
+    bucket = string
+    region = string
+  })
+}
+variable "tags" {
+  type = map(string)
+}
+variable "zones" {
+  type = list(string)
+}
+
+resource "aws_s3_bucket" "test" {
+  bucket = "test-${var.environment}-${var.project}"
+  tags   = var.tags
+}
+
+terraform {
+  backend "s3" {
+  }
+}
@@ -0,0 +1,26 @@
+variable "region" {}
+variable "environment" {}
+variable "project" {}
+variable "account" {
+  type = string
+}
+variable "name" {
+  type = string
+}
+variable "enabled" {
+  type = bool
+}
+variable "replicas" {
+  type    = number
+  default = 1
+}
+variable "tags" {
+  type    = map(string)
+  default = {}
+}
+
+resource "aws_s3_bucket" "test" {
+  count  = var.enabled ? var.replicas : 0
+  bucket = "${var.project}-${var.name}-${count.index}"
+  tags   = var.tags
+}
@@ -0,0 +1,22 @@
+variable "environment" {}
+variable "db_password" {
+  type = string
+}
+variable "db" {
+  type = object({
+    host = string
+    port = number
+  })
+}
+variable "dsn" {
+  type = string
+}
+variable "api" {
+  type = map(string)
+}
+
+resource "null_resource" "app" {
+  triggers = {
+    db = "${var.db.host}:${var.db.port}"
+  }
+}
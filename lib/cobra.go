@@ -4,12 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/ojizero/gofindup"
 	"github.com/spf13/cobra"
-	"log"
 	"math"
 	"os"
-	"path/filepath"
 	"strings"
 )
 
@@ -23,47 +20,33 @@ func ArgsValidator(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func Vars(cmd cobra.Command, env string, stackPath string) ([]string, map[string]any) {
-	// collect global vars
-	vars := make(map[string]any)
-	var files []string
-
-	// collect global vars
-	f := readVarsFile(cmd, "global.tfvars.json", stackPath, &vars)
-	if f != "" {
-		files = append(files, f)
-	}
-
-	// collect global env vars
-	f = readVarsFile(cmd, fmt.Sprintf("%s.tfvars.json", strings.ToLower(env)), stackPath+"/../", &vars)
-	if f != "" {
-		files = append(files, f)
-	}
-
-	// collect local vars
-	f = readVarsFile(cmd, "local.tfvars.json", stackPath, &vars)
-	if f != "" {
-		files = append(files, f)
-	}
-
-	// collect stack global vars
-	f = readVarsFile(cmd, "app.tfvars.json", stackPath, &vars)
-	if f != "" {
-		files = append(files, f)
+func Vars(cmd cobra.Command, env string, stackPath string) *VarSet {
+	set, err := CollectVars(env, stackPath, CliOverrides(cmd))
+	if err != nil {
+		cmd.PrintErrf(ErrorColorLine, err.Error())
+		Exit(1)
 	}
+	files, vars := set.Files, set.Vars
 
-	// collect stack env vars
-	f = readVarsFile(cmd, fmt.Sprintf("%s.tfvars.json", strings.ToLower(env)), stackPath, &vars)
-	if f != "" {
-		files = append(files, f)
+	if cmd.Flags().Lookup("strict") != nil {
+		validateVars(cmd, set, stackPath)
 	}
 
 	verbose, _ := cmd.Parent().PersistentFlags().GetBool("verbose")
 	if verbose {
+		cmd.Printf(InfoColorLine, "Project root:")
+		cmd.Printf(WarningColorLine, fmt.Sprintf("%s (%s)", set.Project.Root, set.Project.RootSource))
+		cmd.Println("")
+
+		if set.Project.ConfigFile != "" {
+			cmd.Printf(InfoColorLine, "Using config:")
+			cmd.Printf(WarningColorLine, set.Project.ConfigFile)
+			cmd.Println("")
+		}
 		if len(files) > 0 {
 			cmd.Printf(InfoColorLine, "Collected vars files:")
 			for _, f := range files {
-				cmd.Printf(WarningColorLine, f)
+				cmd.Printf(WarningColorLine, DisplayVarsFile(f))
 			}
 		}
 
@@ -76,16 +59,78 @@ func Vars(cmd cobra.Command, env string, stackPath string) ([]string, map[string
 			}
 		}
 
-		for k, v := range vars {
-			cmd.Printf(WarningColorMap, maxlen, k, VarToString(v))
+		for _, k := range set.SortedKeys() {
+			cmd.Printf(WarningColorMap, maxlen, k, VarToString(set.Redactor.RedactValue(k, vars[k])))
 		}
 	}
-	return files, vars
+	return set
 }
 
+// CliOverrides reads the --var, --var-file and --root flags of the command
+func CliOverrides(cmd cobra.Command) Overrides {
+	var cli Overrides
+	cli.Vars, _ = cmd.Flags().GetStringArray("var")
+	cli.Files, _ = cmd.Flags().GetStringArray("var-file")
+	cli.Root = CliRoot(cmd)
+	return cli
+}
+
+// CliRoot reads the --root flag of the command
+func CliRoot(cmd cobra.Command) string {
+	root, _ := cmd.Flags().GetString("root")
+	return root
+}
+
+// validateVars checks the vars against the schemas and the stack before terraform runs, errors (and warnings with --strict) abort
+func validateVars(cmd cobra.Command, set *VarSet, stackPath string) {
+	schemaErrors, err := set.ValidateSchema()
+	if err != nil {
+		cmd.PrintErrf(ErrorColorLine, err.Error())
+		Exit(1)
+	}
+
+	findings, err := set.Validate(stackPath)
+	if err != nil {
+		cmd.PrintErrf(ErrorColorLine, err.Error())
+		Exit(1)
+	}
+
+	strict, _ := cmd.Flags().GetBool("strict")
+	failed := len(schemaErrors) > 0
+	for _, e := range schemaErrors {
+		cmd.PrintErrf(ErrorColorLine, e.String())
+	}
+	for _, f := range findings {
+		if f.Level == FindingError || strict {
+			failed = true
+			cmd.PrintErrf(ErrorColorLine, f.String())
+		} else {
+			cmd.PrintErrf(WarningColorLine, "Warning: "+f.String())
+		}
+	}
+
+	if failed {
+		cmd.PrintErrf(ErrorColorLine, "invalid vars for stack "+stackPath)
+		Exit(1)
+	}
+}
+
+// VarToString renders a var for the command line, lists and objects are rendered as json
 func VarToString(v any) string {
 	strVal := ""
 	switch t := v.(type) {
+	case nil:
+		strVal = ""
+	case map[string]any, []any:
+		// json sorts the keys of objects, so the output is stable
+		var b strings.Builder
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(t); err != nil {
+			strVal = fmt.Sprintf("%v", t)
+		} else {
+			strVal = strings.TrimSuffix(b.String(), "\n")
+		}
 	case int:
 		strVal = fmt.Sprintf("%d", t)
 	case float64:
@@ -103,50 +148,30 @@ func VarToString(v any) string {
 	return strVal
 }
 
-func readVarsFile(cmd cobra.Command, name string, path string, vars *map[string]any) string {
-	file, err := gofindup.FindupFrom(name, path)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	if file != "" {
-		content, err := os.ReadFile(file)
-		if err != nil {
-			cmd.PrintErr("error reading file ", file, err)
-			os.Exit(1)
-		}
-		err = json.Unmarshal(content, vars)
-		if err != nil {
-			cmd.PrintErr("error reading json file ", file, err)
-			os.Exit(1)
-		}
-
-		absPath, err := filepath.Abs(file)
-		if err != nil {
-			cmd.PrintErr("error reading file ", file, err)
-			os.Exit(1)
-		}
-		return absPath
-	}
-
-	return ""
+// StateFlag is the name of the flag overriding a var, underscores are dashes, e.g. "state-kms-key-id" for backend.kms_key_id
+func StateFlag(name string) string {
+	return fmt.Sprintf("state-%s", strings.ReplaceAll(name[strings.LastIndex(name, ".")+1:], "_", "-"))
 }
 
+// GetVar reads a var from its "state-<name>" flag (see StateFlag) or the var files, nested keys are addressed with dots, e.g. "backend.bucket"
 func GetVar(name string, cmd cobra.Command, mergedVars map[string]any, required bool) string {
 	var _var string
-	flag := cmd.Flags().Lookup(fmt.Sprintf("state-%s", name))
+	flagName := StateFlag(name)
+	flag := cmd.Flags().Lookup(flagName)
 
 	if flag != nil && flag.Changed {
 		_var = flag.Value.String()
 	} else {
 		if fileVar, ok := mergedVars[name]; ok {
 			_var = VarToString(fileVar)
+		} else if fileVar, ok := lookupPath(mergedVars, name); ok {
+			_var = VarToString(fileVar)
 		}
 	}
 
 	if required && _var == "" {
-		cmd.PrintErrf(ErrorColorLine, fmt.Sprintf("unable to configure remote state, '%s' was not found in var files and not provided with '-state-%s'", name, name))
-		os.Exit(1)
+		cmd.PrintErrf(ErrorColorLine, fmt.Sprintf("unable to configure remote state, '%s' was not found in var files and not provided with '-%s'", name, flagName))
+		Exit(1)
 	}
 
 	return _var
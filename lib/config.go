@@ -0,0 +1,211 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// ConfigFile is the optional project configuration, it is searched upwards from the stack
+const ConfigFile = ".terrarium.yaml"
+
+const (
+	// LocationRoot looks for the var file in the project root only
+	LocationRoot = "root"
+	// LocationStack looks for the var file in the stack directory only
+	LocationStack = "stack"
+	// LocationFindup searches the var file upwards starting at the stack directory
+	LocationFindup = "findup"
+)
+
+type Config struct {
+	Layers    []Layer         `yaml:"layers"`
+	Sensitive SensitiveConfig `yaml:"sensitive"`
+	Merge     MergeConfig     `yaml:"merge"`
+	Sops      SopsConfig      `yaml:"sops"`
+	// Inject maps variable names to built-in sources, defaults to DefaultInject, an empty map disables it
+	Inject map[string]string `yaml:"inject"`
+	// Locked variables must not be overridden by a later layer than the one setting them first
+	Locked []string `yaml:"locked"`
+	// Stacks holds overrides for stacks matching a pattern relative to the root, e.g. "stacks/*"
+	Stacks map[string]StackConfig `yaml:"stacks"`
+	// Workspaces configures normalization, aliases and inheritance of workspaces
+	Workspaces WorkspaceConfig `yaml:"workspaces"`
+	Schema     SchemaConfig    `yaml:"schema"`
+	// Naming overrides the templates of generated backend values by backend type and option, e.g. s3: {bucket: ...}
+	Naming map[string]map[string]string `yaml:"naming"`
+}
+
+// Layer is one level of the var file hierarchy, later layers override earlier ones
+type Layer struct {
+	Name string `yaml:"name"`
+	// File is a template for the file name, e.g. "{{.Workspace}}.tfvars.json"
+	File string `yaml:"file"`
+	// Location is one of root, stack or findup (default)
+	Location string `yaml:"location"`
+	// Path is an optional directory relative to the location, e.g. ".."
+	Path     string `yaml:"path"`
+	Optional bool   `yaml:"optional"`
+}
+
+// LayerData is passed to the layer file templates
+type LayerData struct {
+	Workspace string
+	Stack     string
+	Env       map[string]string
+}
+
+// NewLayerData builds the template data for the given (normalized) workspace and stack
+func NewLayerData(workspace string, stackPath string) LayerData {
+	absStack, _ := filepath.Abs(stackPath)
+	env := make(map[string]string)
+	for _, e := range os.Environ() {
+		if k, v, ok := strings.Cut(e, "="); ok {
+			env[k] = v
+		}
+	}
+
+	return LayerData{Workspace: workspace, Stack: filepath.Base(absStack), Env: env}
+}
+
+// DefaultLayers is the built-in var file hierarchy
+func DefaultLayers() []Layer {
+	return []Layer{
+		{Name: "global", File: "global.tfvars.json", Location: LocationFindup, Optional: true},
+		{Name: "global-env", File: "{{.Workspace}}.tfvars.json", Location: LocationFindup, Path: "..", Optional: true},
+		{Name: "local", File: "local.tfvars.json", Location: LocationFindup, Optional: true},
+		{Name: "app", File: "app.tfvars.json", Location: LocationStack, Optional: true},
+		{Name: "env", File: "{{.Workspace}}.tfvars.json", Location: LocationStack, Optional: true},
+	}
+}
+
+type Project struct {
+	// Root bounds the var file discovery, see findProjectRoot
+	Root string
+	// RootSource tells how the root was found
+	RootSource string
+	// ConfigFile is the loaded config file, empty if none was found
+	ConfigFile string
+	Config     Config
+}
+
+// LoadProject finds the project root and reads the project configuration for the given stack,
+// root is an explicit project root (e.g. given with "--root") or empty
+func LoadProject(stackPath string, root string) (*Project, error) {
+	p := &Project{}
+
+	var err error
+	p.Root, p.RootSource, err = findProjectRoot(stackPath, root)
+	if err != nil {
+		return nil, err
+	}
+	if !p.Contains(stackPath) {
+		return nil, fmt.Errorf("stack %s is outside of the project root %s, use --root to set another one", stackPath, p.Root)
+	}
+
+	file := filepath.Join(p.Root, ConfigFile)
+	content, err := os.ReadFile(file)
+	if err == nil {
+		if err := yaml.Unmarshal(content, &p.Config); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", file, err)
+		}
+		p.ConfigFile = file
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if p.Config.Layers == nil {
+		p.Config.Layers = DefaultLayers()
+	}
+
+	if err := p.Config.Merge.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", p.ConfigFile, err)
+	}
+
+	if err := p.Config.Workspaces.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", p.ConfigFile, err)
+	}
+
+	if err := validateInject(p.Config.Inject); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", p.ConfigFile, err)
+	}
+	for pattern, s := range p.Config.Stacks {
+		if err := validateInject(s.Inject); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: stack %s: %w", p.ConfigFile, pattern, err)
+		}
+	}
+
+	for i, l := range p.Config.Layers {
+		if l.Name == "" || l.File == "" {
+			return nil, fmt.Errorf("invalid config file %s: layer %d needs a name and a file", p.ConfigFile, i+1)
+		}
+		switch l.Location {
+		case "":
+			p.Config.Layers[i].Location = LocationFindup
+		case LocationRoot, LocationStack, LocationFindup:
+		default:
+			return nil, fmt.Errorf("invalid config file %s: unknown location '%s' for layer %s", p.ConfigFile, l.Location, l.Name)
+		}
+	}
+
+	if err := p.Config.Schema.validate(p.Config.Layers); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", p.ConfigFile, err)
+	}
+
+	if err := validateNaming(p.Config.Naming); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", p.ConfigFile, err)
+	}
+
+	return p, nil
+}
+
+// FileStem renders the layer file template and strips a known var file extension,
+// so all supported formats can be looked up for that layer
+func (l Layer) FileStem(data LayerData) (string, error) {
+	tpl, err := template.New(l.Name).Option("missingkey=error").Parse(l.File)
+	if err != nil {
+		return "", fmt.Errorf("invalid file template for layer %s: %w", l.Name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("invalid file template for layer %s: %w", l.Name, err)
+	}
+
+	name := buf.String()
+	for _, ext := range varFileExtensions {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext), nil
+		}
+	}
+	return name, nil
+}
+
+// FindLayerFile looks up a var file of the layer, returns an empty string if there is none
+func (p *Project) FindLayerFile(l Layer, name string, stackPath string) (string, error) {
+	var file string
+	switch l.Location {
+	case LocationRoot:
+		file = filepath.Join(p.Root, l.Path, name)
+	case LocationStack:
+		file = filepath.Join(stackPath, l.Path, name)
+	default:
+		var err error
+		file, err = p.findUp(name, filepath.Join(stackPath, l.Path))
+		if err != nil || file == "" {
+			return "", err
+		}
+	}
+
+	if _, err := os.Stat(file); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return file, p.ensureContains(file)
+}
@@ -0,0 +1,135 @@
+package lib
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// workspacePlaceholder is rendered into the layer templates to find the files of all workspaces
+const workspacePlaceholder = "__terrarium_workspace__"
+
+// DiscoverStacks finds all directories below the project root with terraform files, hidden directories are skipped
+func (p *Project) DiscoverStacks() ([]string, error) {
+	var stacks []string
+	err := filepath.WalkDir(p.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != p.Root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if strings.HasSuffix(d.Name(), ".tf") || strings.HasSuffix(d.Name(), ".tf.json") {
+			dir := filepath.Dir(path)
+			if !contains(stacks, dir) {
+				stacks = append(stacks, dir)
+			}
+		}
+		return nil
+	})
+	sort.Strings(stacks)
+	return stacks, err
+}
+
+// DiscoverWorkspaces finds the workspaces of a stack by the var files of the layers depending on the workspace,
+// the workspaces of the config (aliases and inheritance) are always included
+func (p *Project) DiscoverWorkspaces(stackPath string) ([]string, error) {
+	data := NewLayerData(workspacePlaceholder, stackPath)
+
+	// files of layers which dont depend on the workspace are never workspace files, e.g. global.tfvars.json
+	var fixed []string
+	var patterns []Layer
+	for _, l := range p.Config.Layers {
+		stem, err := l.FileStem(data)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(stem, workspacePlaceholder) {
+			patterns = append(patterns, l)
+		} else {
+			fixed = append(fixed, filepath.Base(stem))
+		}
+	}
+
+	var workspaces []string
+	add := func(w string) {
+		if w = p.Config.Workspaces.Workspace(w); w != "" && !contains(workspaces, w) {
+			workspaces = append(workspaces, w)
+		}
+	}
+
+	for _, l := range patterns {
+		stem, _ := l.FileStem(data)
+		if strings.Contains(filepath.Dir(stem), workspacePlaceholder) {
+			// workspace directories are not supported for discovery
+			continue
+		}
+		pattern := regexp.MustCompile("^" + strings.Replace(regexp.QuoteMeta(filepath.Base(stem)), workspacePlaceholder, "(.+)", 1) + "$")
+
+		for _, dir := range p.layerDirs(l, filepath.Dir(stem), stackPath) {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, e := range entries {
+				if e.IsDir() {
+					continue
+				}
+				name := e.Name()
+				for _, ext := range varFileExtensions {
+					if !strings.HasSuffix(name, ext) {
+						continue
+					}
+					name = strings.TrimSuffix(name, ext)
+					if m := pattern.FindStringSubmatch(name); m != nil && !contains(fixed, name) {
+						add(m[1])
+					}
+					break
+				}
+			}
+		}
+	}
+
+	for k, v := range p.Config.Workspaces.Aliases {
+		add(k)
+		add(v)
+	}
+	for k, v := range p.Config.Workspaces.Extends {
+		add(k)
+		add(v)
+	}
+
+	sort.Strings(workspaces)
+	return workspaces, nil
+}
+
+// layerDirs lists the directories a layer looks for its files, for findup all directories up to the root
+func (p *Project) layerDirs(l Layer, sub string, stackPath string) []string {
+	switch l.Location {
+	case LocationRoot:
+		return []string{filepath.Join(p.Root, l.Path, sub)}
+	case LocationStack:
+		return []string{filepath.Join(stackPath, l.Path, sub)}
+	}
+
+	var dirs []string
+	dir, err := filepath.Abs(filepath.Join(stackPath, l.Path))
+	if err != nil {
+		return nil
+	}
+	for p.Contains(dir) {
+		dirs = append(dirs, filepath.Join(dir, sub))
+		if dir == filepath.Dir(dir) {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	return dirs
+}
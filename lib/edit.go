@@ -0,0 +1,220 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// editableExtension is the only var file format which can be edited without losing formatting
+const editableExtension = ".tfvars.json"
+
+// LayerFile returns the json var file of the layer for the workspace and stack and whether it exists,
+// a missing findup file is placed in the project root
+func (p *Project) LayerFile(layerName string, workspace string, stackPath string) (string, bool, error) {
+	var layer *Layer
+	names := make([]string, 0, len(p.Config.Layers))
+	for i, l := range p.Config.Layers {
+		names = append(names, l.Name)
+		if l.Name == layerName {
+			layer = &p.Config.Layers[i]
+		}
+	}
+	if layer == nil {
+		return "", false, fmt.Errorf("unknown layer %s, use one of %s", layerName, strings.Join(names, ", "))
+	}
+
+	stem, err := layer.FileStem(NewLayerData(p.Config.Workspaces.Workspace(workspace), stackPath))
+	if err != nil {
+		return "", false, err
+	}
+
+	var other string
+	for _, ext := range varFileExtensions {
+		file, err := p.FindLayerFile(*layer, stem+ext, stackPath)
+		if err != nil {
+			return "", false, err
+		}
+		if file == "" {
+			continue
+		}
+		if ext == editableExtension {
+			abs, err := filepath.Abs(file)
+			return abs, true, err
+		}
+		other = file
+	}
+	if other != "" {
+		return "", false, fmt.Errorf("only json var files can be edited, found %s", other)
+	}
+
+	var file string
+	switch layer.Location {
+	case LocationRoot:
+		file = filepath.Join(p.Root, layer.Path, stem+editableExtension)
+	case LocationStack:
+		file = filepath.Join(stackPath, layer.Path, stem+editableExtension)
+	default:
+		file = filepath.Join(p.Root, stem+editableExtension)
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", false, err
+	}
+	return abs, false, p.ensureContains(abs)
+}
+
+// jsonMember is a top level key of a json var file with its value as written in the file
+type jsonMember struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// VarValue is a variable to set with EditVarsFile
+type VarValue struct {
+	Name  string
+	Value any
+}
+
+// EditVarsFile sets and removes variables of a json var file (created if missing),
+// the order of the keys and the formatting of untouched values are preserved, it returns the removed keys
+func EditVarsFile(file string, set []VarValue, unset []string) ([]string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	members, indent, err := parseJsonObject(content)
+	if err != nil {
+		return nil, fmt.Errorf("unable to edit %s: %w", file, err)
+	}
+	for _, m := range members {
+		if m.Key == "sops" {
+			return nil, fmt.Errorf("unable to edit %s: the file is encrypted with sops", file)
+		}
+	}
+
+	for _, v := range set {
+		value, err := marshalJsonValue(v.Value, indent)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", v.Name, err)
+		}
+
+		found := false
+		for i := range members {
+			if members[i].Key == v.Name {
+				members[i].Value = value
+				found = true
+			}
+		}
+		if !found {
+			members = append(members, jsonMember{Key: v.Name, Value: value})
+		}
+	}
+
+	var removed []string
+	kept := members[:0]
+	for _, m := range members {
+		if contains(unset, m.Key) {
+			if !contains(removed, m.Key) {
+				removed = append(removed, m.Key)
+			}
+			continue
+		}
+		kept = append(kept, m)
+	}
+
+	out, err := renderJsonObject(kept, indent)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+		out = bytes.TrimSuffix(out, []byte("\n"))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return nil, err
+	}
+	return removed, os.WriteFile(file, out, 0644)
+}
+
+// parseJsonObject splits a json object into its members and detects the indentation of the keys
+func parseJsonObject(content []byte) ([]jsonMember, string, error) {
+	indent := "  "
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, indent, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(content))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, "", fmt.Errorf("expected a json object")
+	}
+
+	var members []jsonMember
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, "", err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, "", err
+		}
+		members = append(members, jsonMember{Key: t.(string), Value: value})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, "", err
+	}
+
+	// the whitespace in front of the first key
+	if start := bytes.IndexByte(content, '{'); start >= 0 {
+		rest := content[start+1:]
+		if nl := bytes.IndexByte(rest, '\n'); nl >= 0 {
+			line := rest[nl+1:]
+			if w := len(line) - len(bytes.TrimLeft(line, " \t")); w > 0 && w < len(line) && line[w] == '"' {
+				indent = string(line[:w])
+			}
+		}
+	}
+	return members, indent, nil
+}
+
+func renderJsonObject(members []jsonMember, indent string) ([]byte, error) {
+	if len(members) == 0 {
+		return []byte("{}\n"), nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	for i, m := range members {
+		key, err := marshalJsonValue(m.Key, indent)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(indent)
+		buf.Write(key)
+		buf.WriteString(": ")
+		buf.Write(m.Value)
+		if i < len(members)-1 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes(), nil
+}
+
+// marshalJsonValue formats a value for the first level of an object indented with indent
+func marshalJsonValue(v any, indent string) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent(indent, indent)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
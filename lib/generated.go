@@ -0,0 +1,92 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// generatedFiles maps var files written by terrarium itself to the file they were generated from
+var generatedFiles = make(map[string]string)
+
+// generatedLock guards generatedFiles, Cleanup also runs when terrarium is interrupted
+var generatedLock sync.Mutex
+
+// writeGeneratedVarsFile writes vars as a temporary *.tfvars.json file terraform can consume,
+// the file lives until Cleanup is called
+func writeGeneratedVarsFile(source string, vars map[string]any) (string, error) {
+	content, err := json.MarshalIndent(vars, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp("", "terrarium-*.tfvars.json")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(content); err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+
+	generatedLock.Lock()
+	generatedFiles[f.Name()] = source
+	generatedLock.Unlock()
+	return f.Name(), nil
+}
+
+// DisplayVarsFile names the origin of a var file handed over to terraform
+func DisplayVarsFile(file string) string {
+	generatedLock.Lock()
+	defer generatedLock.Unlock()
+	if source, ok := generatedFiles[file]; ok {
+		return fmt.Sprintf("%s (generated %s)", source, file)
+	}
+	return file
+}
+
+// Cleanup removes all var files terrarium generated during this run
+func Cleanup() {
+	generatedLock.Lock()
+	defer generatedLock.Unlock()
+	for f := range generatedFiles {
+		_ = os.Remove(f)
+		delete(generatedFiles, f)
+	}
+}
+
+// Exit removes all generated var files (they might contain decrypted secrets) before exiting
+func Exit(code int) {
+	Cleanup()
+	os.Exit(code)
+}
+
+// CleanupOnSignal removes all generated var files when terrarium is interrupted or terminated (e.g. Ctrl-C
+// while terraform runs) and exits, the returned function stops listening for the signals
+func CleanupOnSignal() func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			code := 1
+			if s, ok := sig.(syscall.Signal); ok {
+				code = 128 + int(s)
+			}
+			Exit(code)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
@@ -0,0 +1,44 @@
+package lib
+
+import (
+	"encoding/json"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// parseHclVars reads a plain HCL var file (e.g. "app.tfvars") into the given vars map
+func parseHclVars(content []byte, file string, vars map[string]any) error {
+	f, diags := hclsyntax.ParseConfig(content, file, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return diags
+	}
+
+	attrs, diags := f.Body.JustAttributes()
+	if diags.HasErrors() {
+		return diags
+	}
+
+	values := make(map[string]any, len(attrs))
+	for name, attr := range attrs {
+		val, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return diags
+		}
+
+		raw, err := ctyjson.Marshal(val, val.Type())
+		if err != nil {
+			return err
+		}
+		values[name] = json.RawMessage(raw)
+	}
+
+	normalized, err := normalizeVars(values)
+	if err != nil {
+		return err
+	}
+	for name, v := range normalized {
+		vars[name] = v
+	}
+	return nil
+}
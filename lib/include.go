@@ -0,0 +1,137 @@
+package lib
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IncludeDirective lists var files (relative to the including file) whose values are applied before the file's own values
+const IncludeDirective = "$include"
+
+// directives are the keys of a var file which configure terrarium instead of setting variables
+var directives = []string{IncludeDirective, LockedDirective}
+
+// loadVarsFile reads a var file with its includes into the layer and returns the file terraform should receive
+func (s *VarSet) loadVarsFile(layer string, file string) (string, error) {
+	values, passFile, err := s.readVarsFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	if !hasDirectives(values) {
+		s.set(layer, file, values)
+		return passFile, nil
+	}
+
+	combined, included, err := s.resolveIncludes(layer, file, values, []string{file})
+	if err != nil {
+		return "", err
+	}
+
+	// terraform neither knows the directives nor the included files, so it receives the combined values
+	if len(included) == 0 {
+		return writeGeneratedVarsFile(fmt.Sprintf("%s without directives", file), combined)
+	}
+	rel := make([]string, 0, len(included))
+	for _, f := range included {
+		if r, err := filepath.Rel(filepath.Dir(file), f); err == nil {
+			f = r
+		}
+		rel = append(rel, f)
+	}
+	return writeGeneratedVarsFile(fmt.Sprintf("%s with includes %s", file, strings.Join(rel, ", ")), combined)
+}
+
+// resolveIncludes applies the included files (depth first) and then the own values of the file to the layer,
+// it returns the combined values and all included files, chain holds the including files to detect cycles
+func (s *VarSet) resolveIncludes(layer string, file string, values map[string]any, chain []string) (map[string]any, []string, error) {
+	includes, err := includeList(file, values[IncludeDirective])
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.lockedDirective(layer, file, values[LockedDirective]); err != nil {
+		return nil, nil, err
+	}
+
+	own := make(map[string]any, len(values))
+	for k, v := range values {
+		if !strings.HasPrefix(k, "$") {
+			own[k] = v
+		} else if !contains(directives, k) {
+			return nil, nil, fmt.Errorf("unknown directive %s in %s", k, file)
+		}
+	}
+
+	combined := make(map[string]any)
+	var included []string
+	for _, inc := range includes {
+		if !filepath.IsAbs(inc) {
+			inc = filepath.Join(filepath.Dir(file), inc)
+		}
+		inc = filepath.Clean(inc)
+
+		if contains(chain, inc) {
+			return nil, nil, fmt.Errorf("include cycle detected: %s", strings.Join(append(chain, inc), " -> "))
+		}
+		if err := s.Project.ensureContains(inc); err != nil {
+			return nil, nil, fmt.Errorf("invalid include in %s: %w", file, err)
+		}
+
+		incValues, _, err := s.readVarsFile(inc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid include in %s: %w", file, err)
+		}
+
+		incCombined, incIncluded, err := s.resolveIncludes(layer, inc, incValues, append(chain[:len(chain):len(chain)], inc))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		s.mergeInto(combined, incCombined)
+		included = append(append(included, inc), incIncluded...)
+	}
+
+	s.set(layer, file, own)
+	s.mergeInto(combined, own)
+
+	return combined, included, nil
+}
+
+// mergeInto applies values the same way set does, so the combined file matches the collected vars
+func (s *VarSet) mergeInto(target map[string]any, values map[string]any) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if old, ok := target[k]; ok && s.Project.Config.Merge.Enabled {
+			target[k] = s.Project.Config.Merge.mergeValue(k, old, values[k])
+		} else {
+			target[k] = values[k]
+		}
+	}
+}
+
+func hasDirectives(values map[string]any) bool {
+	for k := range values {
+		if strings.HasPrefix(k, "$") {
+			return true
+		}
+	}
+	return false
+}
+
+func includeList(file string, v any) ([]string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	includes, err := stringList(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s in %s, expected a list of file names", IncludeDirective, file)
+	}
+	return includes, nil
+}
@@ -0,0 +1,236 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// InjectLayer names the variables terrarium injects itself
+const InjectLayer = "inject"
+
+const (
+	// InjectWorkspace is the workspace name as given on the command line
+	InjectWorkspace = "workspace"
+	// InjectStack is the name of the stack directory
+	InjectStack = "stack"
+	// InjectGitSha is the commit the stack is checked out at
+	InjectGitSha = "git_sha"
+	// InjectGitBranch is the branch the stack is checked out at
+	InjectGitBranch = "git_branch"
+	// InjectVersion is the terrarium version
+	InjectVersion = "version"
+	// InjectUser is the user running terrarium
+	InjectUser = "user"
+	// InjectTimestamp is the start of the run in UTC (RFC 3339)
+	InjectTimestamp = "timestamp"
+)
+
+var injectSources = []string{InjectWorkspace, InjectStack, InjectGitSha, InjectGitBranch, InjectVersion, InjectUser, InjectTimestamp}
+
+// Version of terrarium, set by main
+var Version = "dev"
+
+// StackConfig overrides the project config for stacks matching its pattern
+type StackConfig struct {
+	// Inject is merged into the project wide inject config, an empty source removes a variable
+	Inject map[string]string `yaml:"inject"`
+	// Unlocked variables may be overridden in the stacks despite being locked
+	Unlocked []string `yaml:"unlocked"`
+}
+
+// DefaultInject passes the workspace as "environment", like terrarium always did
+func DefaultInject() map[string]string {
+	return map[string]string{"environment": InjectWorkspace}
+}
+
+func validateInject(inject map[string]string) error {
+	for name, source := range inject {
+		if source != "" && !contains(injectSources, source) {
+			return fmt.Errorf("unknown inject source '%s' for %s, use one of %s", source, name, strings.Join(injectSources, ", "))
+		}
+	}
+	return nil
+}
+
+// StackConfigs returns the configs of all "stacks" patterns (relative to the root) matching the stack, in order of their patterns
+func (p *Project) StackConfigs(stackPath string) ([]StackConfig, error) {
+	abs, err := filepath.Abs(stackPath)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(p.Root, abs)
+	if err != nil {
+		return nil, err
+	}
+	rel = filepath.ToSlash(rel)
+
+	patterns := make([]string, 0, len(p.Config.Stacks))
+	for pattern := range p.Config.Stacks {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	var configs []StackConfig
+	for _, pattern := range patterns {
+		matched, err := filepath.Match(strings.TrimSuffix(pattern, "/"), rel)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stack pattern '%s': %w", pattern, err)
+		}
+		if matched {
+			configs = append(configs, p.Config.Stacks[pattern])
+		}
+	}
+	return configs, nil
+}
+
+// Inject returns the variables to inject into the stack mapped to their source
+func (p *Project) Inject(stackPath string) (map[string]string, error) {
+	inject := make(map[string]string)
+	if p.Config.Inject == nil {
+		inject = DefaultInject()
+	}
+	for k, v := range p.Config.Inject {
+		inject[k] = v
+	}
+
+	configs, err := p.StackConfigs(stackPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range configs {
+		for k, v := range c.Inject {
+			if v == "" {
+				delete(inject, k)
+			} else {
+				inject[k] = v
+			}
+		}
+	}
+	return inject, nil
+}
+
+// inject sets the configured built-in variables, terraform rejects undeclared variables given with -var,
+// so only variables declared by the stack are injected
+func (s *VarSet) inject(workspace string, stackPath string) error {
+	inject, err := s.Project.Inject(stackPath)
+	if err != nil || len(inject) == 0 {
+		return err
+	}
+
+	declared, err := StackVariables(stackPath)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(inject))
+	for name := range inject {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := declared[name]; !ok {
+			continue
+		}
+
+		source := inject[name]
+		value, err := injectValue(source, workspace, stackPath)
+		if err != nil {
+			return fmt.Errorf("unable to inject %s from %s: %w", name, source, err)
+		}
+		s.set(InjectLayer, source, map[string]any{name: value})
+		s.Injected = append(s.Injected, name)
+	}
+	return nil
+}
+
+// WithoutInjected returns a copy of the set without the values injected by terrarium, they are meant for the stack
+// and must not configure anything else (e.g. an injected "environment" is no azure cloud name for the backend),
+// a variable a var file sets as well keeps the value of that file
+func (s *VarSet) WithoutInjected() *VarSet {
+	out := *s
+	out.Vars = make(map[string]any, len(s.Vars))
+	out.Sources = make(map[string][]Assignment, len(s.Sources))
+	out.Injected = nil
+	for k, v := range s.Vars {
+		out.Vars[k] = v
+	}
+	for k, sources := range s.Sources {
+		out.Sources[k] = sources
+	}
+
+	for _, name := range s.Injected {
+		sources := s.Sources[name]
+		var kept []Assignment
+		for _, a := range sources {
+			if a.Layer != InjectLayer {
+				kept = append(kept, a)
+			}
+		}
+		out.Sources[name] = kept
+
+		switch {
+		case len(kept) == 0:
+			delete(out.Vars, name)
+			delete(out.Sources, name)
+		case sources[len(sources)-1].Layer == InjectLayer:
+			out.Vars[name] = kept[len(kept)-1].Value
+		}
+	}
+	return &out
+}
+
+func injectValue(source string, workspace string, stackPath string) (string, error) {
+	switch source {
+	case InjectWorkspace:
+		return workspace, nil
+	case InjectStack:
+		abs, err := filepath.Abs(stackPath)
+		return filepath.Base(abs), err
+	case InjectGitSha:
+		return git(stackPath, "rev-parse", "HEAD")
+	case InjectGitBranch:
+		return git(stackPath, "rev-parse", "--abbrev-ref", "HEAD")
+	case InjectVersion:
+		return Version, nil
+	case InjectUser:
+		if u, err := user.Current(); err == nil {
+			return u.Username, nil
+		}
+		return os.Getenv("USER"), nil
+	case InjectTimestamp:
+		return time.Now().UTC().Format(time.RFC3339), nil
+	default:
+		return "", fmt.Errorf("unknown inject source '%s'", source)
+	}
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// InjectedVars returns the injected variables as "name=value" for terraform's -var,
+// variables overridden on the command line are left out
+func (s *VarSet) InjectedVars() []string {
+	var vars []string
+	for _, name := range s.Injected {
+		sources := s.Sources[name]
+		if len(sources) > 0 && sources[len(sources)-1].Layer != InjectLayer {
+			continue
+		}
+		vars = append(vars, fmt.Sprintf("%s=%s", name, VarToString(s.Vars[name])))
+	}
+	return vars
+}
@@ -0,0 +1,208 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// interpolationPattern matches "${name}", "${env:NAME}" and the escape sequence "$${"
+var interpolationPattern = regexp.MustCompile(`\$\$\{|\$\{([^}]*)\}`)
+
+type interpolator struct {
+	set *VarSet
+	// resolved holds finished variables, resolving marks the ones in progress (in order) to detect cycles
+	resolved  map[string]any
+	resolving []string
+}
+
+// interpolate resolves references to other variables and to environment variables in all string values,
+// variables referencing sensitive ones become sensitive, it returns the names of the variables which changed
+func (s *VarSet) interpolate() ([]string, error) {
+	i := &interpolator{set: s, resolved: make(map[string]any)}
+
+	var changed []string
+	for _, k := range s.SortedKeys() {
+		v, err := i.variable(k)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(v, s.Vars[k]) {
+			changed = append(changed, k)
+		}
+	}
+
+	for _, k := range changed {
+		s.Vars[k] = i.resolved[k]
+	}
+	return changed, nil
+}
+
+// origin names the file which set the final value of a variable
+func (s *VarSet) origin(name string) string {
+	sources := s.Sources[name]
+	if len(sources) == 0 {
+		return "unknown"
+	}
+	return sources[len(sources)-1].File
+}
+
+func (i *interpolator) variable(name string) (any, error) {
+	if v, ok := i.resolved[name]; ok {
+		return v, nil
+	}
+
+	for n, r := range i.resolving {
+		if r == name {
+			var chain []string
+			for _, c := range append(i.resolving[n:], name) {
+				chain = append(chain, fmt.Sprintf("%s (%s)", c, i.set.origin(c)))
+			}
+			return nil, fmt.Errorf("interpolation cycle detected: %s", strings.Join(chain, " -> "))
+		}
+	}
+
+	i.resolving = append(i.resolving, name)
+	v, err := i.value(name, i.set.Vars[name])
+	i.resolving = i.resolving[:len(i.resolving)-1]
+	if err != nil {
+		return nil, err
+	}
+
+	i.resolved[name] = v
+	return v, nil
+}
+
+func (i *interpolator) value(name string, v any) (any, error) {
+	switch t := v.(type) {
+	case string:
+		return i.string(name, t)
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, e := range t {
+			r, err := i.value(name, e)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []any:
+		out := make([]any, 0, len(t))
+		for _, e := range t {
+			r, err := i.value(name, e)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, r)
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+func (i *interpolator) string(name string, s string) (any, error) {
+	matches := interpolationPattern.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s, nil
+	}
+
+	// a single reference keeps the type of the referenced value, e.g. "${tags}"
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(s) && matches[0][2] >= 0 {
+		ref := s[matches[0][2]:matches[0][3]]
+		if !i.resolves(ref) {
+			return s, nil
+		}
+		return i.reference(name, ref)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(s[last:m[0]])
+		last = m[1]
+
+		if m[2] < 0 {
+			// escaped "$${"
+			b.WriteString("${")
+			continue
+		}
+		if !i.resolves(s[m[2]:m[3]]) {
+			b.WriteString(s[m[0]:m[1]])
+			continue
+		}
+
+		r, err := i.reference(name, s[m[2]:m[3]])
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(VarToString(r))
+	}
+	b.WriteString(s[last:])
+
+	return b.String(), nil
+}
+
+// resolves reports whether terrarium resolves a reference, only environment variables and known variables are,
+// everything else is kept as it is, e.g. IAM policy variables like "${aws:username}"
+func (i *interpolator) resolves(ref string) bool {
+	ref = strings.TrimSpace(ref)
+	if strings.HasPrefix(ref, "env:") {
+		return true
+	}
+	if strings.Contains(ref, ":") {
+		return false
+	}
+	key, _, _ := strings.Cut(ref, ".")
+	_, ok := i.set.Vars[key]
+	return ok
+}
+
+func (i *interpolator) reference(name string, ref string) (any, error) {
+	ref = strings.TrimSpace(ref)
+
+	if strings.HasPrefix(ref, "env:") {
+		env := strings.TrimPrefix(ref, "env:")
+		v, ok := os.LookupEnv(env)
+		if !ok {
+			return nil, fmt.Errorf("unable to interpolate '%s' in %s (%s): environment variable %s is not set", ref, name, i.set.origin(name), env)
+		}
+		return v, nil
+	}
+
+	key, path, _ := strings.Cut(ref, ".")
+	v, err := i.variable(key)
+	if err != nil {
+		return nil, err
+	}
+	// a value containing a secret is a secret as well
+	if i.set.Redactor.IsSensitive(key) || i.set.Redactor.IsSensitive(ref) {
+		i.set.Redactor.names[name] = true
+	}
+	if path == "" {
+		return v, nil
+	}
+
+	nested, ok := lookupPath(v, path)
+	if !ok {
+		return nil, fmt.Errorf("unable to interpolate '%s' in %s (%s): %s has no key %s", ref, name, i.set.origin(name), key, path)
+	}
+	return nested, nil
+}
+
+// lookupPath resolves a dotted path like "backend.bucket" inside nested objects
+func lookupPath(v any, path string) (any, bool) {
+	for _, k := range strings.Split(path, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[k]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
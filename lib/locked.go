@@ -0,0 +1,93 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+)
+
+// LockedDirective lists variables of the first layer (global by default) which later layers must not override
+const LockedDirective = "$locked"
+
+// lock marks the variables as locked by the given file (a var file or the config file)
+func (s *VarSet) lock(file string, names []string) {
+	if s.locks == nil {
+		s.locks = make(map[string]string)
+	}
+	for _, name := range names {
+		if _, ok := s.locks[name]; !ok {
+			s.locks[name] = file
+		}
+	}
+}
+
+// lockedDirective reads the $locked directive of a var file, it is only allowed in the first layer
+func (s *VarSet) lockedDirective(layer string, file string, v any) error {
+	if v == nil {
+		return nil
+	}
+	if first := s.Project.Config.Layers[0].Name; layer != first {
+		return fmt.Errorf("%s in %s is only allowed in the %s layer", LockedDirective, file, first)
+	}
+
+	names, err := stringList(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s in %s, expected a list of variable names", LockedDirective, file)
+	}
+	s.lock(file, names)
+	return nil
+}
+
+// checkLocked fails if a locked variable is overridden by a later layer than the one setting it first,
+// unless the stack config unlocks it
+func (s *VarSet) checkLocked(stackPath string) error {
+	if len(s.locks) == 0 {
+		return nil
+	}
+
+	configs, err := s.Project.StackConfigs(stackPath)
+	if err != nil {
+		return err
+	}
+	var unlocked []string
+	for _, c := range configs {
+		unlocked = append(unlocked, c.Unlocked...)
+	}
+
+	names := make([]string, 0, len(s.locks))
+	for name := range s.locks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		sources := s.Sources[name]
+		if len(sources) == 0 || contains(unlocked, name) {
+			continue
+		}
+		for _, a := range sources[1:] {
+			if a.Layer != sources[0].Layer {
+				return fmt.Errorf("variable %s is locked by %s and must not be overridden by %s (layer %s)", name, s.locks[name], a.File, a.Layer)
+			}
+		}
+	}
+	return nil
+}
+
+func stringList(v any) ([]string, error) {
+	switch t := v.(type) {
+	case string:
+		return []string{t}, nil
+	case []any:
+		list := make([]string, 0, len(t))
+		for _, e := range t {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a string, got %v", e)
+			}
+			list = append(list, s)
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("expected a list of strings, got %v", v)
+	}
+}
@@ -0,0 +1,97 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// MergeReplace lets a later layer replace the whole value (terraform behaviour)
+	MergeReplace = "replace"
+	// MergeDeep merges nested objects key by key, lists are replaced
+	MergeDeep = "merge"
+	// MergeAppend merges nested objects key by key and concatenates lists
+	MergeAppend = "append"
+)
+
+type MergeConfig struct {
+	// Enabled deep merges all layers into one generated var file
+	Enabled bool `yaml:"enabled"`
+	// Default strategy for all variables, defaults to merge
+	Default string `yaml:"default"`
+	// Strategies per variable, nested keys are addressed with dots, e.g. "tags" or "network.subnets"
+	Strategies map[string]string `yaml:"strategies"`
+}
+
+func (m MergeConfig) validate() error {
+	strategies := map[string]string{"default": m.Default}
+	for k, v := range m.Strategies {
+		strategies[k] = v
+	}
+
+	for k, v := range strategies {
+		switch v {
+		case "", MergeReplace, MergeDeep, MergeAppend:
+		default:
+			return fmt.Errorf("unknown merge strategy '%s' for %s, use replace, merge or append", v, k)
+		}
+	}
+	return nil
+}
+
+// strategy finds the strategy for the key path, nested keys inherit the strategy of their parents
+func (m MergeConfig) strategy(path string) string {
+	for p := path; p != ""; {
+		if s, ok := m.Strategies[p]; ok && s != "" {
+			return s
+		}
+		i := strings.LastIndex(p, ".")
+		if i < 0 {
+			break
+		}
+		p = p[:i]
+	}
+
+	if m.Default != "" {
+		return m.Default
+	}
+	return MergeDeep
+}
+
+// mergeValue combines the value of an earlier layer with the one of a later layer
+func (m MergeConfig) mergeValue(path string, old any, new any) any {
+	strategy := m.strategy(path)
+	if strategy == MergeReplace {
+		return new
+	}
+
+	if oldList, ok := old.([]any); ok {
+		if newList, ok := new.([]any); ok && strategy == MergeAppend {
+			merged := make([]any, 0, len(oldList)+len(newList))
+			return append(append(merged, oldList...), newList...)
+		}
+		return new
+	}
+
+	oldMap, ok := old.(map[string]any)
+	if !ok {
+		return new
+	}
+	newMap, ok := new.(map[string]any)
+	if !ok {
+		return new
+	}
+
+	merged := make(map[string]any, len(oldMap))
+	for k, v := range oldMap {
+		merged[k] = v
+	}
+	for k, v := range newMap {
+		if o, exists := oldMap[k]; exists {
+			merged[k] = m.mergeValue(path+"."+k, o, v)
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
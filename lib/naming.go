@@ -0,0 +1,82 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"text/template"
+)
+
+// NamingData is passed to the backend naming templates
+type NamingData struct {
+	// Vars are the merged variables
+	Vars      map[string]any
+	Workspace string
+	Stack     string
+	Env       map[string]string
+}
+
+// NewNamingData builds the template data for the backend naming of the stack
+func NewNamingData(set *VarSet, stackPath string) NamingData {
+	layer := NewLayerData(set.Workspace, stackPath)
+	return NamingData{Vars: set.Vars, Workspace: set.Workspace, Stack: layer.Stack, Env: layer.Env}
+}
+
+// namingFuncs are only used to parse the templates, RenderNaming replaces them
+var namingFuncs = template.FuncMap{
+	"var":      func(string) string { return "" },
+	"required": func(string) string { return "" },
+}
+
+// validateNaming parses all naming templates of the config, by backend type and option
+func validateNaming(naming map[string]map[string]string) error {
+	for backend, options := range naming {
+		for option, text := range options {
+			if _, err := parseNaming(backend+"."+option, text, namingFuncs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func parseNaming(name string, text string, funcs template.FuncMap) (*template.Template, error) {
+	tpl, err := template.New(name).Option("missingkey=error").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid naming template for %s: %w", name, err)
+	}
+	return tpl, nil
+}
+
+// RenderNaming renders the naming template of a backend option,
+// lookup resolves a backend variable for the "var" and "required" template functions
+func RenderNaming(name string, text string, data NamingData, lookup func(name string, required bool) string) (string, error) {
+	tpl, err := parseNaming(name, text, template.FuncMap{
+		"var":      func(v string) string { return lookup(v, false) },
+		"required": func(v string) string { return lookup(v, true) },
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("invalid naming template for %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// Naming returns the configured naming template of a backend option, empty if there is none
+func (p *Project) Naming(backend string, option string) string {
+	return p.Config.Naming[backend][option]
+}
+
+// NamingOptions lists the configured naming templates of a backend
+func (p *Project) NamingOptions(backend string) []string {
+	options := make([]string, 0, len(p.Config.Naming[backend]))
+	for o := range p.Config.Naming[backend] {
+		options = append(options, o)
+	}
+	sort.Strings(options)
+	return options
+}
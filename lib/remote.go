@@ -0,0 +1,103 @@
+package lib
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+// RemoteSource resolves references to values stored outside of the var files,
+// e.g. "vault:secret/data/app#password" is resolved by the source registered for "vault"
+type RemoteSource interface {
+	// Resolve returns the value of the reference without the scheme, e.g. "secret/data/app#password"
+	Resolve(ref string) (any, error)
+}
+
+// remoteSources creates the sources by scheme, a source is created once per run and only if it is referenced
+var remoteSources = map[string]func() (RemoteSource, error){
+	"vault": newVaultSource,
+}
+
+// RegisterRemoteSource makes a source available for references with the given scheme
+func RegisterRemoteSource(scheme string, factory func() (RemoteSource, error)) {
+	remoteSources[scheme] = factory
+}
+
+var remoteReferencePattern = regexp.MustCompile(`^([a-z][a-z0-9]*):(.+)$`)
+
+type remoteResolver struct {
+	sources map[string]RemoteSource
+}
+
+// resolveRemote replaces all string values which are references to a remote source (the whole string),
+// it returns the names of the variables which changed, they are always redacted
+func (s *VarSet) resolveRemote() ([]string, error) {
+	r := &remoteResolver{sources: make(map[string]RemoteSource)}
+
+	var changed []string
+	for _, k := range s.SortedKeys() {
+		v, err := r.value(s.Vars[k])
+		if err != nil {
+			return nil, fmt.Errorf("unable to resolve %s (%s): %w", k, s.origin(k), err)
+		}
+		if !reflect.DeepEqual(v, s.Vars[k]) {
+			changed = append(changed, k)
+			s.Vars[k] = v
+			s.Redactor.names[k] = true
+		}
+	}
+	return changed, nil
+}
+
+func (r *remoteResolver) value(v any) (any, error) {
+	switch t := v.(type) {
+	case string:
+		m := remoteReferencePattern.FindStringSubmatch(t)
+		if m == nil {
+			return t, nil
+		}
+		factory, ok := remoteSources[m[1]]
+		if !ok {
+			return t, nil
+		}
+
+		source, ok := r.sources[m[1]]
+		if !ok {
+			var err error
+			if source, err = factory(); err != nil {
+				return nil, err
+			}
+			r.sources[m[1]] = source
+		}
+		return source.Resolve(m[2])
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		out := make(map[string]any, len(t))
+		for _, k := range keys {
+			e, err := r.value(t[k])
+			if err != nil {
+				return nil, err
+			}
+			out[k] = e
+		}
+		return out, nil
+	case []any:
+		out := make([]any, 0, len(t))
+		for _, e := range t {
+			resolved, err := r.value(e)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, resolved)
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
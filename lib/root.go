@@ -0,0 +1,86 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// findProjectRoot determines the boundary for the var file discovery of a stack: the explicit root given with "--root",
+// otherwise the nearest directory above the stack with a .terrarium.yaml or a .git, without any the whole parent chain is searched
+func findProjectRoot(stackPath string, explicit string) (root string, source string, err error) {
+	if explicit != "" {
+		root, err = filepath.Abs(explicit)
+		return root, "--root", err
+	}
+
+	stack, err := filepath.Abs(stackPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	for dir := stack; ; dir = filepath.Dir(dir) {
+		for _, marker := range []string{ConfigFile, ".git"} {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				return dir, marker, nil
+			}
+		}
+		if dir == filepath.Dir(dir) {
+			return dir, fmt.Sprintf("no %s or .git found", ConfigFile), nil
+		}
+	}
+}
+
+// Contains tells if the path is inside of the project root (symlinks are resolved)
+func (p *Project) Contains(path string) bool {
+	root, err := filepath.EvalSymlinks(p.Root)
+	if err != nil {
+		return false
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	} else if resolved, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		abs = filepath.Join(resolved, filepath.Base(abs))
+	}
+
+	rel, err := filepath.Rel(root, abs)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ensureContains fails for files outside of the project root
+func (p *Project) ensureContains(file string) error {
+	if !p.Contains(file) {
+		return fmt.Errorf("var file %s is outside of the project root %s", file, p.Root)
+	}
+	return nil
+}
+
+// findUp searches the file upwards starting at dir, but never leaves the project root
+func (p *Project) findUp(name string, dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for p.Contains(dir) {
+		file := filepath.Join(dir, name)
+		if _, err := os.Stat(file); err == nil {
+			return file, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		if dir == filepath.Dir(dir) {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	return "", nil
+}
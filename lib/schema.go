@@ -0,0 +1,180 @@
+package lib
+
+import (
+	"fmt"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SchemaConfig declares JSON Schemas for the var files, paths are relative to the project root
+type SchemaConfig struct {
+	// Merged validates the merged vars of all layers
+	Merged string `yaml:"merged"`
+	// Layers validate every file of a layer on its own, by layer name
+	Layers map[string]string `yaml:"layers"`
+}
+
+func (c SchemaConfig) validate(layers []Layer) error {
+	for name := range c.Layers {
+		known := name == CliLayer
+		for _, l := range layers {
+			known = known || l.Name == name
+		}
+		if !known {
+			return fmt.Errorf("schema for unknown layer %s", name)
+		}
+	}
+	return nil
+}
+
+// SchemaError is a violation of a var file schema
+type SchemaError struct {
+	File string `json:"file"`
+	// Pointer is the JSON pointer of the invalid value
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+func (e SchemaError) String() string {
+	pointer := e.Pointer
+	if pointer == "" {
+		pointer = "/"
+	}
+	return fmt.Sprintf("%s: %s: %s", e.File, pointer, e.Message)
+}
+
+// additionalPropertyPattern finds the property names of "additionalProperties 'foo', 'bar' not allowed"
+var additionalPropertyPattern = regexp.MustCompile(`'([^']+)'`)
+
+// ValidateSchema validates every file of the layers with a schema and the merged vars against the configured schemas
+func (s *VarSet) ValidateSchema() ([]SchemaError, error) {
+	config := s.Project.Config.Schema
+	var errs []SchemaError
+
+	layers := make([]string, 0, len(config.Layers))
+	for l := range config.Layers {
+		layers = append(layers, l)
+	}
+	sort.Strings(layers)
+
+	for _, layer := range layers {
+		schema, err := s.Project.compileSchema(config.Layers[layer])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range s.layerFiles(layer) {
+			found, err := validateSchema(schema, s.fileValues(file), func(string) string { return file })
+			if err != nil {
+				return nil, err
+			}
+			errs = append(errs, found...)
+		}
+	}
+
+	if config.Merged != "" {
+		schema, err := s.Project.compileSchema(config.Merged)
+		if err != nil {
+			return nil, err
+		}
+
+		found, err := validateSchema(schema, s.Vars, s.origin)
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, found...)
+	}
+
+	return errs, nil
+}
+
+func (p *Project) compileSchema(file string) (*jsonschema.Schema, error) {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(p.Root, file)
+	}
+	schema, err := jsonschema.Compile(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %w", file, err)
+	}
+	return schema, nil
+}
+
+// layerFiles returns the files which set values for the layer
+func (s *VarSet) layerFiles(layer string) []string {
+	var files []string
+	for _, sources := range s.Sources {
+		for _, a := range sources {
+			if a.Layer == layer && !contains(files, a.File) {
+				files = append(files, a.File)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// fileValues returns the values a single file set
+func (s *VarSet) fileValues(file string) map[string]any {
+	values := make(map[string]any)
+	for k, sources := range s.Sources {
+		for _, a := range sources {
+			if a.File == file {
+				values[k] = a.Value
+			}
+		}
+	}
+	return values
+}
+
+// validateSchema validates the vars and names the file of every error with origin (by top level key)
+func validateSchema(schema *jsonschema.Schema, vars map[string]any, origin func(string) string) ([]SchemaError, error) {
+	// the validator only knows the types encoding/json produces
+	doc, err := normalizeVars(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	err = schema.Validate(doc)
+	if err == nil {
+		return nil, nil
+	}
+	ve, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return nil, err
+	}
+
+	// only the leaves name the actual problems
+	var leaves []*jsonschema.ValidationError
+	var walk func(*jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			leaves = append(leaves, e)
+		}
+		for _, c := range e.Causes {
+			walk(c)
+		}
+	}
+	walk(ve)
+
+	var errs []SchemaError
+	for _, e := range leaves {
+		pointer := e.InstanceLocation
+		// point to the offending key instead of the object, e.g. for a typo
+		if strings.HasPrefix(e.Message, "additionalProperties ") {
+			if m := additionalPropertyPattern.FindStringSubmatch(e.Message); m != nil {
+				pointer = pointer + "/" + m[1]
+			}
+		}
+
+		file := "merged vars"
+		if key := strings.SplitN(strings.TrimPrefix(pointer, "/"), "/", 2)[0]; key != "" {
+			file = origin(strings.ReplaceAll(strings.ReplaceAll(key, "~1", "/"), "~0", "~"))
+		}
+
+		errs = append(errs, SchemaError{File: file, Pointer: pointer, Message: e.Message})
+	}
+	return errs, nil
+}
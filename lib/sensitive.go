@@ -0,0 +1,118 @@
+package lib
+
+import (
+	"path"
+	"strings"
+)
+
+// SensitiveValue replaces the value of sensitive variables in all output
+const SensitiveValue = "(sensitive value)"
+
+// DefaultSensitivePatterns mark variables as sensitive by name
+var DefaultSensitivePatterns = []string{"*password*", "*secret*", "*token*"}
+
+type SensitiveConfig struct {
+	// Patterns are case-insensitive globs matched against variable names
+	Patterns []string `yaml:"patterns"`
+	// Vars are explicitly sensitive variable names
+	Vars []string `yaml:"vars"`
+}
+
+// Redactor decides which variables must not be printed
+type Redactor struct {
+	patterns []string
+	names    map[string]bool
+}
+
+// NewRedactor combines the configured patterns and names with the variables declared as sensitive in the stack
+func NewRedactor(project *Project, stackPath string) (*Redactor, error) {
+	r := &Redactor{names: make(map[string]bool)}
+
+	patterns := project.Config.Sensitive.Patterns
+	if patterns == nil {
+		patterns = DefaultSensitivePatterns
+	}
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, err
+		}
+		r.patterns = append(r.patterns, strings.ToLower(p))
+	}
+
+	for _, name := range project.Config.Sensitive.Vars {
+		r.names[name] = true
+	}
+
+	declared, err := StackVariables(stackPath)
+	if err != nil {
+		return nil, err
+	}
+	for name, v := range declared {
+		if v.Sensitive {
+			r.names[name] = true
+		}
+	}
+
+	return r, nil
+}
+
+// StackRedactor builds the Redactor for a stack from its project configuration
+func StackRedactor(stackPath string, root string) (*Redactor, error) {
+	project, err := LoadProject(stackPath, root)
+	if err != nil {
+		return nil, err
+	}
+	return NewRedactor(project, stackPath)
+}
+
+// IsSensitive tells if the variable (or backend setting) with the given name is sensitive
+func (r *Redactor) IsSensitive(name string) bool {
+	if r.names[name] {
+		return true
+	}
+
+	lower := strings.ToLower(name)
+	for _, p := range r.patterns {
+		if ok, _ := path.Match(p, lower); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactValue masks the value if the variable is sensitive, keys of nested objects are checked by their dotted path
+// and by their own name, e.g. "db.password" and "password"
+func (r *Redactor) RedactValue(name string, v any) any {
+	if r.IsSensitive(name) {
+		return SensitiveValue
+	}
+
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, e := range t {
+			if r.IsSensitive(k) {
+				out[k] = SensitiveValue
+			} else {
+				out[k] = r.RedactValue(name+"."+k, e)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, 0, len(t))
+		for _, e := range t {
+			out = append(out, r.RedactValue(name, e))
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// Redact masks the value if the variable is sensitive
+func (r *Redactor) Redact(name string, value string) string {
+	if r.IsSensitive(name) {
+		return SensitiveValue
+	}
+	return value
+}
@@ -0,0 +1,89 @@
+package lib
+
+import (
+	"github.com/getsops/sops/v3/cmd/sops/formats"
+	"github.com/getsops/sops/v3/decrypt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"strings"
+	"sync"
+)
+
+// SopsConfig configures the decryption of sops encrypted var files
+type SopsConfig struct {
+	// AgeKeyFile holds the age identities, relative to the project root,
+	// defaults to $SOPS_AGE_KEY_FILE or the sops default location
+	AgeKeyFile string `yaml:"age_key_file"`
+}
+
+// sopsAgeKeyFileEnv is read by sops to find the age identities
+const sopsAgeKeyFileEnv = "SOPS_AGE_KEY_FILE"
+
+// sopsEnvLock guards the environment while sops reads the configured age key file
+var sopsEnvLock sync.Mutex
+
+// sopsSecrets returns the top level keys of a sops encrypted json or yaml document holding encrypted values,
+// it returns false if the document is not encrypted at all
+func sopsSecrets(content []byte) (map[string]bool, bool) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, false
+	}
+	meta, ok := doc["sops"].(map[string]any)
+	if !ok || meta["mac"] == nil {
+		return nil, false
+	}
+
+	secrets := make(map[string]bool)
+	for k, v := range doc {
+		if k != "sops" && sopsEncrypted(v) {
+			secrets[k] = true
+		}
+	}
+	return secrets, true
+}
+
+func sopsEncrypted(v any) bool {
+	switch t := v.(type) {
+	case string:
+		return strings.HasPrefix(t, "ENC[")
+	case map[string]any:
+		for _, e := range t {
+			if sopsEncrypted(e) {
+				return true
+			}
+		}
+	case []any:
+		for _, e := range t {
+			if sopsEncrypted(e) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decryptSops decrypts a sops encrypted json or yaml document in memory with the upstream sops implementation,
+// keyFile overrides the age identities sops would use otherwise
+func decryptSops(content []byte, format formats.Format, keyFile string) ([]byte, error) {
+	if keyFile == "" {
+		return decrypt.DataWithFormat(content, format)
+	}
+
+	sopsEnvLock.Lock()
+	defer sopsEnvLock.Unlock()
+
+	previous, ok := os.LookupEnv(sopsAgeKeyFileEnv)
+	defer func() {
+		if ok {
+			_ = os.Setenv(sopsAgeKeyFileEnv, previous)
+		} else {
+			_ = os.Unsetenv(sopsAgeKeyFileEnv)
+		}
+	}()
+	if err := os.Setenv(sopsAgeKeyFileEnv, keyFile); err != nil {
+		return nil, err
+	}
+
+	return decrypt.DataWithFormat(content, format)
+}
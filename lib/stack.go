@@ -0,0 +1,191 @@
+package lib
+
+import (
+	"fmt"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// StackVariable is a "variable" block declared by the stack
+type StackVariable struct {
+	Name      string
+	Sensitive bool
+	// Type is the declared type constraint, cty.DynamicPseudoType if there is none
+	Type cty.Type
+	// Required variables have no default value
+	Required bool
+}
+
+var variableBlockSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "variable", LabelNames: []string{"name"}},
+	},
+}
+
+var variableSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "sensitive"},
+		{Name: "type"},
+		{Name: "default"},
+	},
+}
+
+var terraformBlockSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "terraform"},
+	},
+}
+
+var backendBlockSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "backend", LabelNames: []string{"type"}},
+		{Type: "cloud"},
+	},
+}
+
+// CloudBackend is the type of a "cloud" block (Terraform Cloud or Enterprise)
+const CloudBackend = "cloud"
+
+// StackBackend is the backend the stack declares in its terraform block
+type StackBackend struct {
+	// Type is the backend type, e.g. s3, or CloudBackend
+	Type string
+	// Range is where the block is declared
+	Range hcl.Range
+}
+
+func (b StackBackend) String() string {
+	return fmt.Sprintf("%s (%s:%d)", b.Type, b.Range.Filename, b.Range.Start.Line)
+}
+
+// parseStackFiles parses all *.tf and *.tf.json files in the root module of the stack
+func parseStackFiles(stackPath string) ([]*hcl.File, error) {
+	entries, err := os.ReadDir(stackPath)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := hclparse.NewParser()
+	var files []*hcl.File
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		var f *hcl.File
+		var diags hcl.Diagnostics
+		name := filepath.Join(stackPath, e.Name())
+		switch {
+		case strings.HasSuffix(e.Name(), ".tf"):
+			f, diags = parser.ParseHCLFile(name)
+		case strings.HasSuffix(e.Name(), ".tf.json"):
+			f, diags = parser.ParseJSONFile(name)
+		default:
+			continue
+		}
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		files = append(files, f)
+	}
+
+	return files, nil
+}
+
+// StackVariables returns all variables declared by the stack
+func StackVariables(stackPath string) (map[string]StackVariable, error) {
+	files, err := parseStackFiles(stackPath)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := make(map[string]StackVariable)
+	for _, f := range files {
+		content, _, diags := f.Body.PartialContent(variableBlockSchema)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		for _, block := range content.Blocks {
+			v := StackVariable{Name: block.Labels[0], Type: cty.DynamicPseudoType}
+
+			attrs, _, diags := block.Body.PartialContent(variableSchema)
+			if diags.HasErrors() {
+				return nil, diags
+			}
+
+			if attr, ok := attrs.Attributes["sensitive"]; ok {
+				val, diags := attr.Expr.Value(nil)
+				if diags.HasErrors() || !val.Type().Equals(cty.Bool) || val.IsNull() {
+					return nil, fmt.Errorf("%s: variable %s: sensitive must be a bool", attr.Range.String(), v.Name)
+				}
+				v.Sensitive = val.True()
+			}
+
+			if attr, ok := attrs.Attributes["type"]; ok {
+				ty, _, diags := typeexpr.TypeConstraintWithDefaults(attr.Expr)
+				if diags.HasErrors() {
+					return nil, diags
+				}
+				v.Type = ty
+			}
+
+			_, hasDefault := attrs.Attributes["default"]
+			v.Required = !hasDefault
+
+			vars[v.Name] = v
+		}
+	}
+
+	return vars, nil
+}
+
+// FindStackBackend returns the backend (or cloud) block of the stack's root module, nil if there is none,
+// declaring more than one is an error
+func FindStackBackend(stackPath string) (*StackBackend, error) {
+	files, err := parseStackFiles(stackPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []StackBackend
+	for _, f := range files {
+		content, _, diags := f.Body.PartialContent(terraformBlockSchema)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		for _, tf := range content.Blocks {
+			blocks, _, diags := tf.Body.PartialContent(backendBlockSchema)
+			if diags.HasErrors() {
+				return nil, diags
+			}
+
+			for _, block := range blocks.Blocks {
+				b := StackBackend{Type: CloudBackend, Range: block.DefRange}
+				if block.Type == "backend" {
+					b.Type = block.Labels[0]
+				}
+				found = append(found, b)
+			}
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &found[0], nil
+	default:
+		list := make([]string, 0, len(found))
+		for _, b := range found {
+			list = append(list, b.String())
+		}
+		return nil, fmt.Errorf("stack %s declares more than one backend: %s", stackPath, strings.Join(list, ", "))
+	}
+}
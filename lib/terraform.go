@@ -25,7 +25,7 @@ func Binary() string {
 	return path
 }
 
-func Executor(cmd cobra.Command, workspace string, path string, switchWorkspace bool) (*tfexec.Terraform, context.Context, []string, map[string]any) {
+func Executor(cmd cobra.Command, workspace string, path string, switchWorkspace bool) (*tfexec.Terraform, context.Context, *VarSet) {
 	binary, err := cmd.Parent().PersistentFlags().GetString("terraform")
 
 	if err != nil {
@@ -46,16 +46,17 @@ func Executor(cmd cobra.Command, workspace string, path string, switchWorkspace
 
 	ctx := context.Background()
 
+	set := Vars(cmd, workspace, path)
+
+	// the workspace is normalized the same way for the var files and terraform
 	if switchWorkspace {
-		ensureAndSwitchWorkspace(tf, ctx, cmd, workspace)
+		ensureAndSwitchWorkspace(tf, ctx, cmd, set.Project.Config.Workspaces, set.Workspace)
 	}
 
-	files, vars := Vars(cmd, workspace, path)
-
-	return tf, context.Background(), files, vars
+	return tf, context.Background(), set
 }
 
-func ensureAndSwitchWorkspace(tf *tfexec.Terraform, ctx context.Context, cmd cobra.Command, name string) {
+func ensureAndSwitchWorkspace(tf *tfexec.Terraform, ctx context.Context, cmd cobra.Command, config WorkspaceConfig, name string) {
 	tf.SetStdout(nil)
 	workspaces, current, err := tf.WorkspaceList(ctx)
 	tf.SetStdout(cmd.OutOrStdout())
@@ -71,6 +72,10 @@ func ensureAndSwitchWorkspace(tf *tfexec.Terraform, ctx context.Context, cmd cob
 			exists = true
 		}
 	}
+	if err := config.CheckExisting(name, workspaces); err != nil {
+		cmd.PrintErrf(ErrorColorLine, err.Error())
+		Exit(1)
+	}
 	if !exists {
 		err := tf.WorkspaceNew(ctx, name)
 		if err != nil {
@@ -0,0 +1,115 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"os"
+	"sort"
+)
+
+const (
+	FindingError   = "error"
+	FindingWarning = "warning"
+)
+
+// Finding is a problem of the collected vars compared to the variables declared by the stack
+type Finding struct {
+	Level   string
+	Name    string
+	File    string
+	Message string
+}
+
+func (f Finding) String() string {
+	if f.File == "" {
+		return fmt.Sprintf("%s: %s", f.Name, f.Message)
+	}
+	return fmt.Sprintf("%s (%s): %s", f.Name, f.File, f.Message)
+}
+
+// Validate compares the collected vars with the "variable" blocks of the stack, it reports
+// missing required variables and values which can't be converted as errors,
+// undeclared variables and values which only convert loosely (e.g. "true" into a bool) as warnings
+func (s *VarSet) Validate(stackPath string) ([]Finding, error) {
+	declared, err := StackVariables(stackPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, name := range s.SortedKeys() {
+		v, ok := declared[name]
+		if !ok {
+			findings = append(findings, Finding{FindingWarning, name, s.origin(name), "variable is not declared by the stack"})
+			continue
+		}
+		if f := s.validateType(v); f != nil {
+			findings = append(findings, *f)
+		}
+	}
+
+	var missing []string
+	for name, v := range declared {
+		if _, ok := s.Vars[name]; ok || !v.Required {
+			continue
+		}
+		// terraform also reads variables from the environment
+		if _, ok := os.LookupEnv("TF_VAR_" + name); ok {
+			continue
+		}
+		missing = append(missing, name)
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		findings = append(findings, Finding{FindingError, name, "", "required variable is not set in any var file"})
+	}
+
+	return findings, nil
+}
+
+func (s *VarSet) validateType(v StackVariable) *Finding {
+	if v.Type == cty.DynamicPseudoType || s.Vars[v.Name] == nil {
+		return nil
+	}
+
+	val, err := ctyValue(s.Vars[v.Name])
+	if err != nil {
+		return &Finding{FindingError, v.Name, s.origin(v.Name), err.Error()}
+	}
+
+	want := typeexpr.TypeString(v.Type)
+	if _, err := convert.Convert(val, v.Type); err != nil {
+		return &Finding{FindingError, v.Name, s.origin(v.Name), fmt.Sprintf("expected %s: %s", want, err.Error())}
+	}
+	// values of --var are always strings, just like terraform's -var
+	if !val.Type().Equals(v.Type) && convert.GetConversion(val.Type(), v.Type) == nil && s.origin(v.Name) != cliVarSource {
+		return &Finding{FindingWarning, v.Name, s.origin(v.Name), fmt.Sprintf("expected %s, got %s which is only converted loosely", want, val.Type().FriendlyName())}
+	}
+	return nil
+}
+
+// ctyValue converts a decoded var into a cty value the way terraform reads json var files
+func ctyValue(v any) (cty.Value, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	ty, err := ctyjson.ImpliedType(b)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return ctyjson.Unmarshal(b, ty)
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
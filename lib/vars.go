@@ -0,0 +1,420 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/getsops/sops/v3/cmd/sops/formats"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// varFileExtensions are the supported var file formats, a layer applies them in this order
+// (same as terraform does for terraform.tfvars and terraform.tfvars.json)
+var varFileExtensions = []string{".tfvars", ".tfvars.json", ".tfvars.yaml", ".tfvars.yml"}
+
+// VarSet is the result of collecting all var files of a stack for a workspace
+type VarSet struct {
+	Project *Project
+	// Workspace is the normalized terraform workspace, see WorkspaceConfig.Workspace
+	Workspace string
+	// Files are handed over to terraform with -var-file, in order
+	Files []string
+	// Vars are the merged variables of all Files
+	Vars map[string]any
+	// Sources lists every assignment of a variable, the last one wins
+	Sources map[string][]Assignment
+	// Redactor masks sensitive values in output
+	Redactor *Redactor
+	// Injected are the names of the built-in variables set by terrarium, see InjectedVars
+	Injected []string
+	// locks maps locked variables to the file locking them, see checkLocked
+	locks map[string]string
+}
+
+// Assignment is a value set by a var file
+type Assignment struct {
+	Layer string `json:"layer"`
+	File  string `json:"file"`
+	Value any    `json:"value"`
+}
+
+// CliLayer names the variables given on the command line
+const CliLayer = "cli"
+
+// cliVarSource is the file name shown for variables given with --var
+const cliVarSource = "--var"
+
+// Overrides are variables given on the command line, they are applied after all layers
+type Overrides struct {
+	// Vars in the form key=value
+	Vars []string
+	// Files are var files in any supported format
+	Files []string
+	// Root is an explicit project root, see LoadProject
+	Root string
+}
+
+// CollectVars reads all layers of the var file hierarchy for the given workspace and stack
+func CollectVars(env string, stackPath string, cli Overrides) (*VarSet, error) {
+	project, err := LoadProject(stackPath, cli.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	redactor, err := NewRedactor(project, stackPath)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &VarSet{
+		Project:  project,
+		Vars:     make(map[string]any),
+		Sources:  make(map[string][]Assignment),
+		Redactor: redactor,
+	}
+
+	workspace := project.Config.Workspaces.Workspace(env)
+	chain, err := project.Config.Workspaces.Chain(workspace)
+	if err != nil {
+		return nil, err
+	}
+	set.Workspace = workspace
+	set.lock(project.ConfigFile, project.Config.Locked)
+
+	data := NewLayerData(workspace, stackPath)
+
+	// collect all layers in order, e.g. global, global env, local, stack global and stack env vars
+	var sourceFiles []string
+	for _, layer := range project.Config.Layers {
+		files, err := set.readLayer(layer, data, chain, stackPath)
+		if err != nil {
+			return nil, err
+		}
+		sourceFiles = append(sourceFiles, files...)
+	}
+
+	if err := set.inject(workspace, stackPath); err != nil {
+		return nil, err
+	}
+
+	files, err := set.readOverrides(cli)
+	if err != nil {
+		return nil, err
+	}
+	sourceFiles = append(sourceFiles, files...)
+
+	if err := set.checkLocked(stackPath); err != nil {
+		return nil, err
+	}
+
+	// remote values are resolved first, so other variables can interpolate them
+	resolved, err := set.resolveRemote()
+	if err != nil {
+		return nil, err
+	}
+
+	changed, err := set.interpolate()
+	if err != nil {
+		return nil, err
+	}
+
+	// terraform replaces whole variables, so hand over the deep merged result instead of the single files
+	if project.Config.Merge.Enabled && len(sourceFiles) > 0 {
+		merged, err := writeGeneratedVarsFile(fmt.Sprintf("deep merge of %s", strings.Join(sourceFiles, ", ")), set.Vars)
+		if err != nil {
+			return nil, err
+		}
+		set.Files = []string{merged}
+	} else {
+		// terraform doesnt interpolate var files, so the resolved values override the raw ones
+		if err := set.overrideVars("interpolated", changed); err != nil {
+			return nil, err
+		}
+		if err := set.overrideVars("remote values of", resolved); err != nil {
+			return nil, err
+		}
+	}
+
+	return set, nil
+}
+
+// overrideVars hands over the current values of the variables as a generated var file, after all others
+func (s *VarSet) overrideVars(description string, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	values := make(map[string]any, len(names))
+	for _, k := range names {
+		values[k] = s.Vars[k]
+	}
+	f, err := writeGeneratedVarsFile(fmt.Sprintf("%s %s", description, strings.Join(names, ", ")), values)
+	if err != nil {
+		return err
+	}
+	s.Files = append(s.Files, f)
+	return nil
+}
+
+// set applies the values of a var file
+func (s *VarSet) set(layer string, file string, values map[string]any) {
+	for k, v := range values {
+		if old, ok := s.Vars[k]; ok && s.Project.Config.Merge.Enabled {
+			s.Vars[k] = s.Project.Config.Merge.mergeValue(k, old, v)
+		} else {
+			s.Vars[k] = v
+		}
+		s.Sources[k] = append(s.Sources[k], Assignment{Layer: layer, File: file, Value: v})
+	}
+}
+
+// readLayer merges all var files found for the layer and returns their paths,
+// layers depending on the workspace are read for every workspace of the chain (see WorkspaceConfig.Chain)
+func (s *VarSet) readLayer(layer Layer, data LayerData, chain []string, stackPath string) ([]string, error) {
+	var stems []string
+	for _, workspace := range chain {
+		data.Workspace = workspace
+		stem, err := layer.FileStem(data)
+		if err != nil {
+			return nil, err
+		}
+		if !contains(stems, stem) {
+			stems = append(stems, stem)
+		}
+	}
+
+	var files []string
+	for _, stem := range stems {
+		found, err := s.readLayerFiles(layer, stem, stackPath)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, found...)
+	}
+
+	if len(files) == 0 && !layer.Optional {
+		return nil, fmt.Errorf("no var file found for required layer '%s' (%s)", layer.Name, strings.Join(stems, ", "))
+	}
+
+	return files, nil
+}
+
+// readLayerFiles reads the files of a layer in all supported formats
+func (s *VarSet) readLayerFiles(layer Layer, stem string, stackPath string) ([]string, error) {
+	var files []string
+	for _, ext := range varFileExtensions {
+		file, err := s.Project.FindLayerFile(layer, stem+ext, stackPath)
+		if err != nil {
+			return nil, err
+		}
+		if file == "" {
+			continue
+		}
+
+		absPath, err := filepath.Abs(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading file %s: %w", file, err)
+		}
+
+		passFile, err := s.loadVarsFile(layer.Name, absPath)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Files = append(s.Files, passFile)
+		files = append(files, absPath)
+	}
+
+	return files, nil
+}
+
+// readOverrides applies the var files and vars given on the command line as the last layer
+func (s *VarSet) readOverrides(cli Overrides) ([]string, error) {
+	var files []string
+	for _, file := range cli.Files {
+		absPath, err := filepath.Abs(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading file %s: %w", file, err)
+		}
+
+		passFile, err := s.loadVarsFile(CliLayer, absPath)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Files = append(s.Files, passFile)
+		files = append(files, absPath)
+	}
+
+	if len(cli.Vars) == 0 {
+		return files, nil
+	}
+
+	values := make(map[string]any, len(cli.Vars))
+	for _, v := range cli.Vars {
+		key, value, ok := strings.Cut(v, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --var '%s', expected key=value", v)
+		}
+
+		// like terraform, lists and objects are hcl expressions, everything else is a plain string
+		if trimmed := strings.TrimSpace(value); strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{") {
+			parsed := make(map[string]any)
+			if err := parseHclVars([]byte(fmt.Sprintf("%s = %s", key, trimmed)), cliVarSource, parsed); err != nil {
+				return nil, fmt.Errorf("invalid --var '%s': %w", v, err)
+			}
+			values[key] = parsed[key]
+		} else {
+			values[key] = value
+		}
+	}
+
+	s.set(CliLayer, cliVarSource, values)
+
+	// terraform receives the values as a var file, so they are ordered like all other layers
+	f, err := writeGeneratedVarsFile(cliVarSource, values)
+	if err != nil {
+		return nil, err
+	}
+	s.Files = append(s.Files, f)
+
+	return append(files, cliVarSource), nil
+}
+
+// readVarsFile parses a var file and returns its values and the file terraform should receive
+func (s *VarSet) readVarsFile(file string) (map[string]any, string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading file %s: %w", file, err)
+	}
+
+	isJson := strings.HasSuffix(file, ".json")
+	isYaml := strings.HasSuffix(file, ".yaml") || strings.HasSuffix(file, ".yml")
+
+	if (isJson || isYaml) && bytes.Contains(content, []byte("sops")) {
+		vars, generated, encrypted, err := s.decryptVarsFile(file, content, isJson)
+		if err != nil {
+			return nil, "", err
+		}
+		if encrypted {
+			return vars, generated, nil
+		}
+	}
+
+	vars := make(map[string]any)
+	switch {
+	case isJson:
+		if err := json.Unmarshal(content, &vars); err != nil {
+			return nil, "", fmt.Errorf("error reading json file %s: %w", file, err)
+		}
+	case isYaml:
+		vars, err = parseYamlVars(content)
+		if err != nil {
+			return nil, "", fmt.Errorf("error reading yaml file %s: %w", file, err)
+		}
+
+		// terraform cant read yaml, so hand over a json copy
+		generated, err := writeGeneratedVarsFile(file, vars)
+		if err != nil {
+			return nil, "", fmt.Errorf("error converting yaml file %s: %w", file, err)
+		}
+		return vars, generated, nil
+	default:
+		if err := parseHclVars(content, file, vars); err != nil {
+			return nil, "", fmt.Errorf("error reading hcl file %s: %w", file, err)
+		}
+	}
+
+	return vars, file, nil
+}
+
+// normalizeVars round trips values through json, so they look exactly like the ones from *.tfvars.json files
+func normalizeVars(vars map[string]any) (map[string]any, error) {
+	raw, err := json.Marshal(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]any)
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// decryptVarsFile decrypts a sops encrypted var file, terraform receives the plaintext as a temporary file
+func (s *VarSet) decryptVarsFile(file string, content []byte, isJson bool) (map[string]any, string, bool, error) {
+	secrets, encrypted := sopsSecrets(content)
+	if !encrypted {
+		return nil, "", false, nil
+	}
+
+	keyFile := s.Project.Config.Sops.AgeKeyFile
+	if keyFile != "" && !filepath.IsAbs(keyFile) {
+		keyFile = filepath.Join(s.Project.Root, keyFile)
+	}
+
+	format := formats.Yaml
+	if isJson {
+		format = formats.Json
+	}
+	plain, err := decryptSops(content, format, keyFile)
+	if err != nil {
+		return nil, "", true, fmt.Errorf("error decrypting sops file %s: %w", file, err)
+	}
+
+	// the plaintext is json or yaml, both are read by the yaml parser
+	vars, err := parseYamlVars(plain)
+	if err != nil {
+		return nil, "", true, fmt.Errorf("error reading decrypted sops file %s: %w", file, err)
+	}
+
+	// never print decrypted values
+	for k := range secrets {
+		s.Redactor.names[k] = true
+	}
+
+	generated, err := writeGeneratedVarsFile(file, vars)
+	if err != nil {
+		return nil, "", true, fmt.Errorf("error writing decrypted sops file %s: %w", file, err)
+	}
+	return vars, generated, true, nil
+}
+
+// VarOrigin returns the file which set the current value of a variable, nested keys are addressed with dots,
+// e.g. "backend.bucket", it is empty if the variable is not set
+func (s *VarSet) VarOrigin(name string) string {
+	top, path, nested := strings.Cut(name, ".")
+	sources := s.Sources[top]
+	if len(sources) == 0 {
+		return ""
+	}
+	if !nested {
+		return sources[len(sources)-1].File
+	}
+	if _, ok := lookupPath(s.Vars[top], path); !ok {
+		return ""
+	}
+
+	for i := len(sources) - 1; i >= 0; i-- {
+		if _, ok := lookupPath(sources[i].Value, path); ok {
+			return sources[i].File
+		}
+	}
+	// the nested key only exists in the resolved value, e.g. of a remote reference
+	return sources[len(sources)-1].File
+}
+
+// SortedKeys returns the variable names in alphabetical order
+func (s *VarSet) SortedKeys() []string {
+	keys := make([]string, 0, len(s.Vars))
+	for k := range s.Vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,102 @@
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// vaultCache holds the secrets read during this run by address and path, so every secret is read once
+var vaultCache = make(map[string]map[string]any)
+
+// vaultSource reads secrets from a Vault KV v2 engine, references look like "secret/data/app#password",
+// without "#key" the whole secret is returned as an object
+type vaultSource struct {
+	addr      string
+	token     string
+	namespace string
+	client    *http.Client
+}
+
+func newVaultSource() (RemoteSource, error) {
+	addr := strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/")
+	if addr == "" {
+		return nil, errors.New("VAULT_ADDR is not set")
+	}
+
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		return nil, errors.New("VAULT_TOKEN is not set")
+	}
+
+	return &vaultSource{
+		addr:      addr,
+		token:     token,
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (v *vaultSource) Resolve(ref string) (any, error) {
+	path, key, hasKey := strings.Cut(ref, "#")
+
+	secret, err := v.read(strings.Trim(path, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if !hasKey {
+		return secret, nil
+	}
+
+	value, ok := secret[key]
+	if !ok {
+		return nil, fmt.Errorf("key %s not found in vault secret %s", key, path)
+	}
+	return value, nil
+}
+
+func (v *vaultSource) read(path string) (map[string]any, error) {
+	cacheKey := v.addr + "/" + path
+	if secret, ok := vaultCache[cacheKey]; ok {
+		return secret, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/%s", v.addr, path), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read vault secret %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+		Errors []string `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("invalid response for vault secret %s: %w", path, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to read vault secret %s: %s %s", path, resp.Status, strings.Join(body.Errors, ", "))
+	}
+	if body.Data.Data == nil {
+		return nil, fmt.Errorf("vault secret %s has no data, is it a KV v2 path like secret/data/app?", path)
+	}
+
+	vaultCache[cacheKey] = body.Data.Data
+	return body.Data.Data, nil
+}
@@ -0,0 +1,117 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// NormalizeLower lower-cases workspace names (default)
+	NormalizeLower = "lower"
+	// NormalizeNone keeps workspace names as given
+	NormalizeNone = "none"
+)
+
+// WorkspaceConfig controls how the workspace given on the command line is mapped
+// to the terraform workspace and to the workspace var files
+type WorkspaceConfig struct {
+	// Normalize is lower (default) or none
+	Normalize string `yaml:"normalize"`
+	// Aliases map alternative names to a workspace, e.g. "production: prod"
+	Aliases map[string]string `yaml:"aliases"`
+	// Extends lets a workspace inherit the var files of another one, e.g. "prod-eu: prod"
+	Extends map[string]string `yaml:"extends"`
+}
+
+func (w WorkspaceConfig) validate() error {
+	switch w.Normalize {
+	case "", NormalizeLower, NormalizeNone:
+	default:
+		return fmt.Errorf("unknown workspace normalization '%s', use lower or none", w.Normalize)
+	}
+
+	// aliases which are equal after the normalization have to point to the same workspace
+	seen := make(map[string]string)
+	aliases := make([]string, 0, len(w.Aliases))
+	for alias := range w.Aliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		name := w.normalize(alias)
+		if other, ok := seen[name]; ok && w.normalize(w.Aliases[other]) != w.normalize(w.Aliases[alias]) {
+			return fmt.Errorf("workspace aliases %s and %s collide, but point to %s and %s", other, alias, w.Aliases[other], w.Aliases[alias])
+		}
+		seen[name] = alias
+	}
+
+	for name := range w.Extends {
+		if _, err := w.Chain(w.Workspace(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w WorkspaceConfig) normalize(name string) string {
+	if w.Normalize == NormalizeNone {
+		return name
+	}
+	return strings.ToLower(name)
+}
+
+// Workspace maps a workspace given on the command line to its terraform workspace,
+// it is normalized first and then resolved through the aliases (which never collide, see validate)
+func (w WorkspaceConfig) Workspace(name string) string {
+	name = w.normalize(name)
+	for alias, workspace := range w.Aliases {
+		if w.normalize(alias) == name {
+			return w.normalize(workspace)
+		}
+	}
+	return name
+}
+
+// CheckExisting fails if a terraform workspace differs from the normalized workspace only in case,
+// terrarium would create a new and empty workspace instead of using the existing one
+func (w WorkspaceConfig) CheckExisting(workspace string, existing []string) error {
+	if w.Normalize == NormalizeNone {
+		return nil
+	}
+	for _, ws := range existing {
+		if ws == workspace {
+			return nil
+		}
+	}
+	for _, ws := range existing {
+		if strings.EqualFold(ws, workspace) {
+			return fmt.Errorf("terraform workspace %s exists, but workspaces are normalized to %s, rename the workspace or set workspaces.normalize to none in %s", ws, workspace, ConfigFile)
+		}
+	}
+	return nil
+}
+
+// Chain lists the workspace and all workspaces it extends, the most basic one first
+func (w WorkspaceConfig) Chain(workspace string) ([]string, error) {
+	extends := make(map[string]string, len(w.Extends))
+	for k, v := range w.Extends {
+		extends[w.Workspace(k)] = w.Workspace(v)
+	}
+
+	chain := []string{workspace}
+	for parent, ok := extends[workspace]; ok; parent, ok = extends[parent] {
+		for _, c := range chain {
+			if c == parent {
+				return nil, fmt.Errorf("workspace inheritance cycle: %s -> %s", strings.Join(chain, " -> "), parent)
+			}
+		}
+		chain = append(chain, parent)
+	}
+
+	// reverse, so the base workspace comes first
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain, nil
+}
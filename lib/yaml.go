@@ -0,0 +1,15 @@
+package lib
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// parseYamlVars reads a yaml var file (e.g. "app.tfvars.yaml")
+func parseYamlVars(content []byte) (map[string]any, error) {
+	var raw map[string]any
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+
+	return normalizeVars(raw)
+}
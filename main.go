@@ -24,6 +24,7 @@ package main
 import (
 	"fmt"
 	"github.com/terrarium-tf/cli/cmd"
+	"github.com/terrarium-tf/cli/lib"
 )
 
 var (
@@ -33,6 +34,8 @@ var (
 )
 
 func main() {
+	lib.Version = version
+
 	rootCmd := cmd.NewRootCommand()
 	rootCmd.Version = fmt.Sprintf("version: %s (%s) - %s", version, commit, date)
 